@@ -0,0 +1,105 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// ErrNotFound est retourné quand aucun compte ne correspond.
+var ErrNotFound = errors.New("account not found")
+
+// Account est l'adresse email d'un utilisateur et son état de vérification. Le mot
+// de passe reste géré par le service d'authentification.
+type Account struct {
+	UserID     string     `json:"user_id"`
+	Username   string     `json:"username"`
+	TenantID   string     `json:"tenant_id"`
+	Email      string     `json:"email"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Verified indique si l'adresse email a été vérifiée.
+func (a *Account) Verified() bool {
+	return a.VerifiedAt != nil
+}
+
+// Repository persiste les comptes dans le store, avec un index par email.
+type Repository struct {
+	store store.Store
+}
+
+// NewRepository crée un repository adossé au store.
+func NewRepository(st store.Store) *Repository {
+	return &Repository{store: st}
+}
+
+func accountKey(userID string) string {
+	return fmt.Sprintf("account:%s", userID)
+}
+
+func emailKey(email string) string {
+	return fmt.Sprintf("account_email:%s", normalize(email))
+}
+
+func normalize(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Create enregistre le compte d'un nouvel utilisateur (adresse non vérifiée).
+func (r *Repository) Create(ctx context.Context, account *Account) error {
+	account.VerifiedAt = nil
+	account.CreatedAt = time.Now().UTC()
+	if err := store.SetJSON(ctx, r.store, accountKey(account.UserID), account, 0); err != nil {
+		return err
+	}
+	return r.store.Set(ctx, emailKey(account.Email), account.UserID, 0)
+}
+
+// Get retourne le compte d'un utilisateur.
+func (r *Repository) Get(ctx context.Context, userID string) (*Account, error) {
+	var account Account
+	if err := store.GetJSON(ctx, r.store, accountKey(userID), &account); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// FindByEmail retourne le compte associé à une adresse (insensible à la casse).
+func (r *Repository) FindByEmail(ctx context.Context, email string) (*Account, error) {
+	userID, err := r.store.Get(ctx, emailKey(email))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return r.Get(ctx, userID)
+}
+
+// MarkVerified marque l'adresse comme vérifiée, si elle est toujours celle du compte.
+func (r *Repository) MarkVerified(ctx context.Context, userID, email string) (*Account, error) {
+	account, err := r.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if normalize(account.Email) != normalize(email) {
+		return nil, ErrNotFound
+	}
+	if account.VerifiedAt == nil {
+		now := time.Now().UTC()
+		account.VerifiedAt = &now
+		if err := store.SetJSON(ctx, r.store, accountKey(userID), account, 0); err != nil {
+			return nil, err
+		}
+	}
+	return account, nil
+}
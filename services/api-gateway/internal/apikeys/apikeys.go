@@ -0,0 +1,282 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// Préfixe des clés en clair, pour les reconnaître (scanners de secrets, logs)
+const keyPrefix = "mck_"
+
+// Intervalle minimal entre deux mises à jour de LastUsedAt, pour éviter une
+// écriture dans le store à chaque requête
+const lastUsedResolution = time.Minute
+
+// Scopes attribuables à une clé
+var Scopes = []string{
+	"files:read",
+	"files:write",
+	"file_requests:read",
+	"file_requests:write",
+	"webhooks:read",
+	"webhooks:write",
+	"notifications:read",
+	"admin",
+}
+
+var (
+	// ErrNotFound est retourné quand la clé n'existe pas ou n'appartient pas à l'utilisateur.
+	ErrNotFound = errors.New("api key not found")
+	// ErrExpired est retourné quand la clé a expiré.
+	ErrExpired = errors.New("api key expired")
+	// ErrUnknownScope est retourné pour un scope inconnu.
+	ErrUnknownScope = errors.New("unknown api key scope")
+	// ErrScopeNotAllowed est retourné quand l'utilisateur demande un scope que son rôle n'autorise pas.
+	ErrScopeNotAllowed = errors.New("api key scope not allowed")
+)
+
+// Key est une clé d'API créée par un utilisateur. Seule son empreinte SHA-256 est
+// conservée ; la clé en clair n'est connue qu'à la création.
+type Key struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Username   string     `json:"username"`
+	Role       string     `json:"role"`
+	TenantID   string     `json:"tenant_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// storedKey ajoute l'empreinte à la représentation persistée (elle n'est jamais exposée par l'API).
+type storedKey struct {
+	Key
+	Hash string `json:"hash"`
+}
+
+// Expired indique si la clé n'est plus utilisable.
+func (k *Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+// HasScope indique si la clé porte le scope demandé.
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Repository persiste les clés dans le store.
+type Repository struct {
+	store store.Store
+}
+
+// NewRepository crée un repository adossé au store.
+func NewRepository(st store.Store) *Repository {
+	return &Repository{store: st}
+}
+
+func keyKey(id string) string {
+	return fmt.Sprintf("apikey:%s", id)
+}
+
+func hashKey(hash string) string {
+	return fmt.Sprintf("apikey_hash:%s", hash)
+}
+
+func userKey(userID string) string {
+	return fmt.Sprintf("apikeys:user:%s", userID)
+}
+
+// Create enregistre une clé et retourne sa valeur en clair.
+func (r *Repository) Create(ctx context.Context, key *Key) (string, error) {
+	for _, scope := range key.Scopes {
+		if !validScope(scope) {
+			return "", fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+		if scope == "admin" && key.Role != "admin" {
+			return "", fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	plaintext := keyPrefix + secret
+
+	key.ID = id
+	key.Prefix = plaintext[:len(keyPrefix)+8]
+	key.Hash = Hash(plaintext)
+	key.LastUsedAt = nil
+	key.CreatedAt = time.Now().UTC()
+
+	if err := r.save(ctx, key); err != nil {
+		return "", err
+	}
+	if err := r.store.Set(ctx, hashKey(key.Hash), id, 0); err != nil {
+		return "", err
+	}
+	if err := r.store.SAdd(ctx, userKey(key.UserID), id); err != nil {
+		return "", err
+	}
+	return plaintext, nil
+}
+
+// Get retourne une clé appartenant à userID.
+func (r *Repository) Get(ctx context.Context, userID, id string) (*Key, error) {
+	key, err := r.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return key, nil
+}
+
+// List retourne les clés de l'utilisateur.
+func (r *Repository) List(ctx context.Context, userID string) ([]Key, error) {
+	ids, err := r.store.SMembers(ctx, userKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]Key, 0, len(ids))
+	for _, id := range ids {
+		key, err := r.load(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+// Revoke supprime une clé de l'utilisateur ; elle est refusée immédiatement.
+func (r *Repository) Revoke(ctx context.Context, userID, id string) error {
+	key, err := r.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := r.store.Delete(ctx, hashKey(key.Hash)); err != nil {
+		return err
+	}
+	if err := r.store.Delete(ctx, keyKey(id)); err != nil {
+		return err
+	}
+	return r.store.SRem(ctx, userKey(userID), id)
+}
+
+// RevokeUser supprime toutes les clés de l'utilisateur (révocation de ses accès,
+// nouveau mot de passe).
+func (r *Repository) RevokeUser(ctx context.Context, userID string) error {
+	ids, err := r.store.SMembers(ctx, userKey(userID))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		key, err := r.load(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.store.Delete(ctx, hashKey(key.Hash), keyKey(id)); err != nil {
+			return err
+		}
+	}
+	return r.store.Delete(ctx, userKey(userID))
+}
+
+// Authenticate retrouve la clé correspondant à une valeur en clair et met à jour
+// sa date de dernière utilisation.
+func (r *Repository) Authenticate(ctx context.Context, plaintext string) (*Key, error) {
+	if !strings.HasPrefix(plaintext, keyPrefix) {
+		return nil, ErrNotFound
+	}
+	id, err := r.store.Get(ctx, hashKey(Hash(plaintext)))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	key, err := r.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if key.Expired(now) {
+		return nil, ErrExpired
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		key.LastUsedAt = &now
+		// Best effort : un échec d'écriture ne doit pas refuser la requête
+		r.save(ctx, key)
+	}
+	return key, nil
+}
+
+// Hash retourne l'empreinte SHA-256 (hexadécimale) d'une clé en clair.
+func Hash(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *Repository) save(ctx context.Context, key *Key) error {
+	return store.SetJSON(ctx, r.store, keyKey(key.ID), storedKey{Key: *key, Hash: key.Hash}, 0)
+}
+
+func (r *Repository) load(ctx context.Context, id string) (*Key, error) {
+	var stored storedKey
+	if err := store.GetJSON(ctx, r.store, keyKey(id), &stored); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	key := stored.Key
+	key.Hash = stored.Hash
+	return &key, nil
+}
+
+func validScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
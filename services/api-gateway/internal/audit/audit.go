@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// Nombre d'entrées conservées dans le journal
+const logSize = 10000
+
+const logKey = "audit_log"
+
+// Entry est une action sensible de sécurité (verrouillage, déblocage, impersonation...).
+type Entry struct {
+	Action    string                 `json:"action"`
+	Actor     string                 `json:"actor,omitempty"`
+	Target    string                 `json:"target,omitempty"`
+	TenantID  string                 `json:"tenant_id,omitempty"`
+	IP        string                 `json:"ip,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Logger enregistre les entrées d'audit.
+type Logger interface {
+	Log(ctx context.Context, entry Entry) error
+}
+
+// Log conserve les entrées dans le store et les écrit aussi dans les logs du service.
+type Log struct {
+	store store.Store
+}
+
+// NewLog crée un journal d'audit adossé au store.
+func NewLog(st store.Store) *Log {
+	return &Log{store: st}
+}
+
+// Log ajoute une entrée au journal.
+func (l *Log) Log(ctx context.Context, entry Entry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	log.Printf("audit: %s", data)
+	return l.store.LPush(ctx, logKey, string(data), logSize)
+}
+
+// List retourne les entrées les plus récentes d'abord, filtrées par action si non vide.
+func (l *Log) List(ctx context.Context, action string, limit int) ([]Entry, error) {
+	items, err := l.store.LRange(ctx, logKey, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0)
+	for _, item := range items {
+		var entry Entry
+		if err := json.Unmarshal([]byte(item), &entry); err != nil {
+			continue
+		}
+		if action != "" && entry.Action != action {
+			continue
+		}
+		entries = append(entries, entry)
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,93 @@
+package clientcert
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Identity est l'utilisateur associé à un certificat client.
+type Identity struct {
+	Match    string // "cn:<nom>", "dns:<nom>", "uri:<uri>" ou "email:<adresse>"
+	UserID   string
+	Username string
+	Role     string
+	TenantID string
+}
+
+// Mapper associe le sujet ou un SAN d'un certificat vérifié à une identité.
+type Mapper struct {
+	identities []Identity
+}
+
+// NewMapper crée un mapper ; la première identité correspondante l'emporte.
+func NewMapper(identities []Identity) *Mapper {
+	return &Mapper{identities: identities}
+}
+
+// ParseIdentity lit une entrée "cn:device-1=user_id:role[:tenant]".
+func ParseIdentity(entry string) (Identity, error) {
+	match, target, ok := strings.Cut(entry, "=")
+	if !ok {
+		return Identity{}, fmt.Errorf("invalid client certificate mapping %q", entry)
+	}
+	kind, value, ok := strings.Cut(match, ":")
+	if !ok || value == "" {
+		return Identity{}, fmt.Errorf("invalid client certificate match %q", match)
+	}
+	switch kind {
+	case "cn", "dns", "uri", "email":
+	default:
+		return Identity{}, fmt.Errorf("unknown client certificate match type %q", kind)
+	}
+
+	parts := strings.Split(target, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Identity{}, fmt.Errorf("invalid client certificate identity %q", target)
+	}
+	identity := Identity{Match: kind + ":" + value, UserID: parts[0], Username: value, Role: parts[1]}
+	if len(parts) == 3 {
+		identity.TenantID = parts[2]
+	}
+	return identity, nil
+}
+
+// Identify retourne l'identité du certificat, ou false s'il n'est pas déclaré.
+func (m *Mapper) Identify(cert *x509.Certificate) (*Identity, bool) {
+	names := certificateNames(cert)
+	for i := range m.identities {
+		if names[m.identities[i].Match] {
+			identity := m.identities[i]
+			return &identity, true
+		}
+	}
+	return nil, false
+}
+
+func certificateNames(cert *x509.Certificate) map[string]bool {
+	names := map[string]bool{"cn:" + cert.Subject.CommonName: cert.Subject.CommonName != ""}
+	for _, dns := range cert.DNSNames {
+		names["dns:"+dns] = true
+	}
+	for _, uri := range cert.URIs {
+		names["uri:"+uri.String()] = true
+	}
+	for _, email := range cert.EmailAddresses {
+		names["email:"+email] = true
+	}
+	return names
+}
+
+// LoadCAPool lit un bundle PEM d'autorités de certification clientes.
+func LoadCAPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
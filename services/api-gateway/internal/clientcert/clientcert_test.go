@@ -0,0 +1,54 @@
+package clientcert
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIdentity(t *testing.T) {
+	identity, err := ParseIdentity("cn:sensor-42=device-42:device:tenant-1")
+	require.NoError(t, err)
+	assert.Equal(t, Identity{Match: "cn:sensor-42", UserID: "device-42", Username: "sensor-42", Role: "device", TenantID: "tenant-1"}, identity)
+
+	identity, err = ParseIdentity("uri:spiffe://cluster/ns/billing=svc-billing:service")
+	require.NoError(t, err)
+	assert.Equal(t, "uri:spiffe://cluster/ns/billing", identity.Match)
+	assert.Empty(t, identity.TenantID)
+
+	for _, entry := range []string{"sensor-42=device-42:device", "ou:ops=ops:admin", "cn:x=device-42", "cn:=a:b"} {
+		_, err := ParseIdentity(entry)
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://cluster/ns/billing")
+	mapper := NewMapper([]Identity{
+		{Match: "cn:sensor-42", UserID: "device-42", Role: "device"},
+		{Match: "dns:files.internal", UserID: "svc-files", Role: "service"},
+		{Match: "uri:spiffe://cluster/ns/billing", UserID: "svc-billing", Role: "service"},
+	})
+
+	identity, ok := mapper.Identify(&x509.Certificate{Subject: pkix.Name{CommonName: "sensor-42"}})
+	assert.True(t, ok)
+	assert.Equal(t, "device-42", identity.UserID)
+
+	identity, ok = mapper.Identify(&x509.Certificate{DNSNames: []string{"other.internal", "files.internal"}})
+	assert.True(t, ok)
+	assert.Equal(t, "svc-files", identity.UserID)
+
+	identity, ok = mapper.Identify(&x509.Certificate{URIs: []*url.URL{spiffe}})
+	assert.True(t, ok)
+	assert.Equal(t, "svc-billing", identity.UserID)
+
+	_, ok = mapper.Identify(&x509.Certificate{Subject: pkix.Name{CommonName: "unknown"}})
+	assert.False(t, ok)
+	// Un certificat sans CN ne correspond pas à "cn:"
+	_, ok = NewMapper([]Identity{{Match: "cn:", UserID: "x", Role: "y"}}).Identify(&x509.Certificate{})
+	assert.False(t, ok)
+}
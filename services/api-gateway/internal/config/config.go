@@ -3,25 +3,204 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// OIDCProvider décrit un fournisseur d'identité OIDC externe.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	RoleClaim    string
+	RoleMappings []RoleMapping
+	DefaultRole  string
+	Tenant       string
+}
+
+// RoleMapping associe une valeur de claim (ex. un groupe) à un rôle du gateway.
+type RoleMapping struct {
+	Value string
+	Role  string
+}
+
 type Config struct {
-	Port           string
-	RedisURL       string
-	AuthServiceURL string
-	FileServiceURL string
-	JWT_SECRET     string
-	RateLimit      int
+	Port                  string
+	RedisURL              string
+	AuthServiceURL        string
+	FileServiceURL        string
+	JWT_SECRET            string
+	JWTKeys               []string
+	JWTHS256AcceptUntil   time.Time
+	JWTKeysReloadInterval time.Duration
+	AccessTokenTTL        time.Duration
+	JWTIssuer             string
+	JWTAudience           []string
+	JWTLeeway             time.Duration
+	JWTLegacyUntil        time.Time
+	RefreshTokenTTL       time.Duration
+	DenylistCacheTTL      time.Duration
+	RateLimit             int
+	MaxUploadSize         int
+	UserQuota             int
+	ImportTimeout         int
+	ImportAllowlist       []string
+	WebhookMaxAttempts    int
+	WebhookRetryDelay     int
+	WebhookTimeout        int
+	WebhookAllowlist      []string
+	TrustedProxies        []string
+	OIDCProviders         []OIDCProvider
+	RolePermissions       map[string][]string
+	PolicyFile            string
+	PolicyReloadInterval  time.Duration
+	DefaultTenant         string
+	TenantRateLimit       int
+	MFAIssuer             string
+	MFAChallengeTTL       time.Duration
+	MFARequiredRoles      []string
+	LoginMaxFailures      int
+	LoginIPMaxFailures    int
+	LoginFailureWindow    time.Duration
+	LoginLockoutDuration  time.Duration
+	LoginDelayBase        time.Duration
+	LoginDelayMax         time.Duration
+	UsernameMinLength     int
+	UsernameMaxLength     int
+	UsernamePattern       string
+	PasswordMinLength     int
+	PasswordMaxLength     int
+	PasswordMinClasses    int
+	BreachedPasswordsFile string
+	PublicURL             string
+	CORSAllowedOrigins    []string
+	EmailVerificationTTL  time.Duration
+	PasswordResetTTL      time.Duration
+	PasswordResetLimit    int
+	PasswordResetWindow   time.Duration
+	SMTPHost              string
+	SMTPPort              int
+	SMTPUsername          string
+	SMTPPassword          string
+	MailFrom              string
+	MailDir               string
+	IntrospectionClients  map[string]string
+	AccessTokenCookie     string
+	RefreshTokenCookie    string
+	CSRFCookie            string
+	CookieDomain          string
+	CookieSecure          bool
+	CookieSameSite        string
+	ForwardAuthLoginURL   string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSClientCAFile       string
+	TLSClientAuth         string
+	ClientCertIdentities  []string
+	ClientCertRoutes      []string
+	// Secret partagé avec les services internes pour signer l'identité propagée
+	IdentitySigningSecret string
+	// Fournisseur d'authentification : "remote" (AUTH_SERVICE_URL) ou "embedded"
+	AuthProvider          string
+	UserDBDriver          string
+	UserDBDSN             string
+	PasswordHashAlgorithm string
+	UserDefaultRole       string
+	BootstrapAdmin        string
+	BootstrapPassword     string
+	// Impersonation par un admin : durée du token et méthodes HTTP refusées
+	ImpersonationTTL     time.Duration
+	ImpersonationBlocked []string
 }
 
 func Load() *Config {
 	return &Config{
-		Port:           getEnv("PORT", "8080"),
-		RedisURL:       getEnv("REDIS_URL", "redis://localhost:6379"),
-		AuthServiceURL: getEnv("AUTH_SERVICE_URL", "http://localhost:8081"),
-		FileServiceURL: getEnv("FILE_SERVICE_URL", "http://localhost:8082"),
-		JWT_SECRET:     getEnv("JWT_SECRET", "secret"),
-		RateLimit:      getEnvAsInt("RATE_LIMIT", 100),
+		Port:                  getEnv("PORT", "8080"),
+		RedisURL:              getEnv("REDIS_URL", "redis://localhost:6379"),
+		AuthServiceURL:        getEnv("AUTH_SERVICE_URL", "http://localhost:8081"),
+		FileServiceURL:        getEnv("FILE_SERVICE_URL", "http://localhost:8082"),
+		JWT_SECRET:            getEnv("JWT_SECRET", "secret"),
+		JWTKeys:               getEnvAsSlice("JWT_KEYS", nil),
+		JWTHS256AcceptUntil:   getEnvAsTime("JWT_HS256_ACCEPT_UNTIL", time.Time{}),
+		JWTKeysReloadInterval: getEnvAsDuration("JWT_KEYS_RELOAD_INTERVAL", time.Hour),
+		AccessTokenTTL:        getEnvAsDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		JWTIssuer:             getEnv("JWT_ISSUER", "mini-cloud-gateway"),
+		JWTAudience:           getEnvAsSlice("JWT_AUDIENCE", []string{"mini-cloud"}),
+		JWTLeeway:             getEnvAsDuration("JWT_LEEWAY", 30*time.Second),
+		JWTLegacyUntil:        getEnvAsTime("JWT_LEGACY_ACCEPT_UNTIL", time.Time{}),
+		RefreshTokenTTL:       getEnvAsDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		DenylistCacheTTL:      getEnvAsDuration("DENYLIST_CACHE_TTL", 5*time.Second),
+		RateLimit:             getEnvAsInt("RATE_LIMIT", 100),
+		MaxUploadSize:         getEnvAsInt("MAX_UPLOAD_SIZE", 100<<20),
+		UserQuota:             getEnvAsInt("USER_QUOTA", 10<<30),
+		ImportTimeout:         getEnvAsInt("IMPORT_TIMEOUT", 300),
+		ImportAllowlist:       getEnvAsSlice("IMPORT_ALLOWLIST", nil),
+		WebhookMaxAttempts:    getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookRetryDelay:     getEnvAsInt("WEBHOOK_RETRY_DELAY", 2),
+		WebhookTimeout:        getEnvAsInt("WEBHOOK_TIMEOUT", 10),
+		WebhookAllowlist:      getEnvAsSlice("WEBHOOK_ALLOWLIST", nil),
+		TrustedProxies:        getEnvAsSlice("TRUSTED_PROXIES", nil),
+		OIDCProviders:         loadOIDCProviders(),
+		RolePermissions:       loadRolePermissions(),
+		PolicyFile:            getEnv("POLICY_FILE", ""),
+		PolicyReloadInterval:  getEnvAsDuration("POLICY_RELOAD_INTERVAL", 30*time.Second),
+		DefaultTenant:         getEnv("DEFAULT_TENANT", "default"),
+		TenantRateLimit:       getEnvAsInt("TENANT_RATE_LIMIT", 0),
+		MFAIssuer:             getEnv("MFA_ISSUER", "MiniCloud"),
+		MFAChallengeTTL:       getEnvAsDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+		MFARequiredRoles:      getEnvAsSlice("MFA_REQUIRED_ROLES", nil),
+		LoginMaxFailures:      getEnvAsInt("LOGIN_MAX_FAILURES", 5),
+		LoginIPMaxFailures:    getEnvAsInt("LOGIN_IP_MAX_FAILURES", 20),
+		LoginFailureWindow:    getEnvAsDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutDuration:  getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginDelayBase:        getEnvAsDuration("LOGIN_DELAY_BASE", time.Second),
+		LoginDelayMax:         getEnvAsDuration("LOGIN_DELAY_MAX", 30*time.Second),
+		UsernameMinLength:     getEnvAsInt("USERNAME_MIN_LENGTH", 3),
+		UsernameMaxLength:     getEnvAsInt("USERNAME_MAX_LENGTH", 32),
+		UsernamePattern:       getEnv("USERNAME_PATTERN", `^[a-zA-Z0-9][a-zA-Z0-9._-]*$`),
+		PasswordMinLength:     getEnvAsInt("PASSWORD_MIN_LENGTH", 10),
+		PasswordMaxLength:     getEnvAsInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordMinClasses:    getEnvAsInt("PASSWORD_MIN_CLASSES", 3),
+		BreachedPasswordsFile: getEnv("BREACHED_PASSWORDS_FILE", ""),
+		PublicURL:             getEnv("PUBLIC_URL", "http://localhost:3000"),
+		CORSAllowedOrigins:    getEnvAsSlice("CORS_ALLOWED_ORIGINS", nil),
+		EmailVerificationTTL:  getEnvAsDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		PasswordResetTTL:      getEnvAsDuration("PASSWORD_RESET_TTL", time.Hour),
+		PasswordResetLimit:    getEnvAsInt("PASSWORD_RESET_LIMIT", 5),
+		PasswordResetWindow:   getEnvAsDuration("PASSWORD_RESET_WINDOW", time.Hour),
+		SMTPHost:              getEnv("SMTP_HOST", ""),
+		SMTPPort:              getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:          getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:          getEnv("SMTP_PASSWORD", ""),
+		MailFrom:              getEnv("MAIL_FROM", "MiniCloud <no-reply@localhost>"),
+		MailDir:               getEnv("MAIL_DIR", ""),
+		IntrospectionClients:  loadIntrospectionClients(),
+		AccessTokenCookie:     getEnv("ACCESS_TOKEN_COOKIE", "mc_access_token"),
+		RefreshTokenCookie:    getEnv("REFRESH_TOKEN_COOKIE", "mc_refresh_token"),
+		CSRFCookie:            getEnv("CSRF_COOKIE", "mc_csrf"),
+		CookieDomain:          getEnv("COOKIE_DOMAIN", ""),
+		CookieSecure:          getEnv("COOKIE_SECURE", "true") != "false",
+		CookieSameSite:        getEnv("COOKIE_SAMESITE", "lax"),
+		TLSCertFile:           getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:            getEnv("TLS_KEY_FILE", ""),
+		TLSClientCAFile:       getEnv("TLS_CLIENT_CA_FILE", ""),
+		TLSClientAuth:         getEnv("TLS_CLIENT_AUTH", "none"),
+		ClientCertIdentities:  loadClientCertIdentities(),
+		ClientCertRoutes:      getEnvAsSlice("CLIENT_CERT_ROUTES", nil),
+		ForwardAuthLoginURL:   getEnv("FORWARD_AUTH_LOGIN_URL", ""),
+		IdentitySigningSecret: getEnv("IDENTITY_SIGNING_SECRET", ""),
+		AuthProvider:          getEnv("AUTH_PROVIDER", "remote"),
+		UserDBDriver:          getEnv("USER_DB_DRIVER", "sqlite"),
+		UserDBDSN:             getEnv("USER_DB_DSN", "users.db"),
+		PasswordHashAlgorithm: getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		UserDefaultRole:       getEnv("USER_DEFAULT_ROLE", "user"),
+		BootstrapAdmin:        getEnv("BOOTSTRAP_ADMIN_USERNAME", ""),
+		BootstrapPassword:     getEnv("BOOTSTRAP_ADMIN_PASSWORD", ""),
+		ImpersonationTTL:      getEnvAsDuration("IMPERSONATION_TTL", 15*time.Minute),
+		ImpersonationBlocked:  getEnvAsSlice("IMPERSONATION_BLOCKED_METHODS", []string{"DELETE"}),
 	}
 }
 
@@ -40,3 +219,118 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsTime(key string, defaultValue time.Time) time.Time {
+	if value := os.Getenv(key); value != "" {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+	return defaultValue
+}
+
+// loadOIDCProviders lit OIDC_PROVIDERS (ex. "corp,google") puis, pour chaque nom,
+// les variables OIDC_<NOM>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL,
+// _SCOPES, _ROLE_CLAIM, _ROLE_MAP ("groupe=role,..."), _DEFAULT_ROLE et _TENANT.
+func loadOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range getEnvAsSlice("OIDC_PROVIDERS", nil) {
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProvider{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       getEnvAsSlice(prefix+"SCOPES", nil),
+			RoleClaim:    getEnv(prefix+"ROLE_CLAIM", ""),
+			DefaultRole:  getEnv(prefix+"DEFAULT_ROLE", "user"),
+			Tenant:       getEnv(prefix+"TENANT", ""),
+		}
+		for _, entry := range getEnvAsSlice(prefix+"ROLE_MAP", nil) {
+			if value, role, ok := strings.Cut(entry, "="); ok {
+				provider.RoleMappings = append(provider.RoleMappings, RoleMapping{Value: value, Role: role})
+			}
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// loadIntrospectionClients lit INTROSPECTION_CLIENTS, ex. "files=secret1,billing=secret2" :
+// les services internes autorisés à appeler l'endpoint d'introspection.
+func loadIntrospectionClients() map[string]string {
+	clients := make(map[string]string)
+	for _, entry := range getEnvAsSlice("INTROSPECTION_CLIENTS", nil) {
+		if id, secret, ok := strings.Cut(entry, "="); ok && id != "" && secret != "" {
+			clients[id] = secret
+		}
+	}
+	return clients
+}
+
+// loadClientCertIdentities lit CLIENT_CERT_IDENTITIES, entrées séparées par ";" :
+// ex. "cn:sensor-42=device-42:device:tenant-1;uri:spiffe://cluster/billing=svc-billing:service".
+// Les virgules sont permises dans les URI.
+func loadClientCertIdentities() []string {
+	var entries []string
+	for _, entry := range strings.Split(os.Getenv("CLIENT_CERT_IDENTITIES"), ";") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Permissions accordées par défaut à chaque rôle
+var defaultRolePermissions = map[string][]string{
+	"admin":        {"*"},
+	"tenant_admin": {"files:*", "tenant:members"},
+	"user":         {"files:read", "files:write", "files:delete"},
+	"readonly":     {"files:read"},
+}
+
+// loadRolePermissions lit ROLE_PERMISSIONS, ex. "admin=*;user=files:read,files:write".
+// Les rôles absents de la variable gardent leurs permissions par défaut.
+func loadRolePermissions() map[string][]string {
+	permissions := make(map[string][]string, len(defaultRolePermissions))
+	for role, perms := range defaultRolePermissions {
+		permissions[role] = perms
+	}
+	for _, entry := range strings.Split(os.Getenv("ROLE_PERMISSIONS"), ";") {
+		role, list, ok := strings.Cut(entry, "=")
+		if role = strings.TrimSpace(role); !ok || role == "" {
+			continue
+		}
+		var perms []string
+		for _, p := range strings.Split(list, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				perms = append(perms, p)
+			}
+		}
+		permissions[role] = perms
+	}
+	return permissions
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/middleware"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRolePermissionsCoverAssignableRoles(t *testing.T) {
+	t.Setenv("ROLE_PERMISSIONS", "")
+	permissions := middleware.Permissions(loadRolePermissions())
+
+	// Un rôle attribuable par un admin de tenant doit avoir des permissions
+	for _, role := range tenants.AssignableRoles {
+		assert.Contains(t, permissions, role)
+	}
+
+	// Un membre en lecture seule liste et télécharge les fichiers, sans les modifier
+	assert.True(t, permissions.Allows("readonly", "files:read"))
+	assert.False(t, permissions.Allows("readonly", "files:write"))
+	assert.False(t, permissions.Allows("readonly", "files:delete"))
+}
@@ -0,0 +1,172 @@
+package filerequests
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+var (
+	// ErrNotFound est retourné quand le lien n'existe pas ou n'appartient pas à l'utilisateur.
+	ErrNotFound = errors.New("file request not found")
+	// ErrExpired est retourné quand le lien a expiré.
+	ErrExpired = errors.New("file request expired")
+)
+
+// FileRequest est un lien public permettant de déposer des fichiers dans le dossier d'un utilisateur.
+type FileRequest struct {
+	Token        string     `json:"token"`
+	OwnerID      string     `json:"owner_id"`
+	TenantID     string     `json:"tenant_id"`
+	Folder       string     `json:"folder"`
+	Title        string     `json:"title,omitempty"`
+	MaxSize      int64      `json:"max_size,omitempty"`
+	AllowedTypes []string   `json:"allowed_types,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Expired indique si le lien n'est plus utilisable.
+func (r *FileRequest) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && now.After(*r.ExpiresAt)
+}
+
+// Allows vérifie qu'un fichier correspond aux types autorisés. Un type peut être
+// une extension (".pdf"), un type MIME ("application/pdf") ou un joker ("image/*").
+func (r *FileRequest) Allows(filename, contentType string) bool {
+	if len(r.AllowedTypes) == 0 {
+		return true
+	}
+
+	ext := strings.ToLower(path.Ext(filename))
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(mime.TypeByExtension(ext))
+	}
+
+	for _, allowed := range r.AllowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		switch {
+		case strings.HasPrefix(allowed, "."):
+			if ext == allowed {
+				return true
+			}
+		case strings.HasSuffix(allowed, "/*"):
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+		case allowed == mediaType:
+			return true
+		}
+	}
+	return false
+}
+
+// Repository persiste les liens de dépôt dans le store.
+type Repository struct {
+	store store.Store
+}
+
+// NewRepository crée un repository adossé au store.
+func NewRepository(st store.Store) *Repository {
+	return &Repository{store: st}
+}
+
+func requestKey(token string) string {
+	return fmt.Sprintf("file_request:%s", token)
+}
+
+func ownerKey(ownerID string) string {
+	return fmt.Sprintf("file_requests:owner:%s", ownerID)
+}
+
+// Create enregistre un nouveau lien et lui attribue un token aléatoire.
+func (r *Repository) Create(ctx context.Context, req *FileRequest) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	req.Token = token
+	req.CreatedAt = time.Now().UTC()
+
+	var ttl time.Duration
+	if req.ExpiresAt != nil {
+		ttl = time.Until(*req.ExpiresAt)
+	}
+	if err := store.SetJSON(ctx, r.store, requestKey(token), req, ttl); err != nil {
+		return err
+	}
+	return r.store.SAdd(ctx, ownerKey(req.OwnerID), token)
+}
+
+// Get retourne un lien valide à partir de son token.
+func (r *Repository) Get(ctx context.Context, token string) (*FileRequest, error) {
+	var req FileRequest
+	if err := store.GetJSON(ctx, r.store, requestKey(token), &req); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if req.Expired(time.Now()) {
+		return nil, ErrExpired
+	}
+	return &req, nil
+}
+
+// ListByOwner retourne les liens encore présents d'un utilisateur.
+func (r *Repository) ListByOwner(ctx context.Context, ownerID string) ([]FileRequest, error) {
+	tokens, err := r.store.SMembers(ctx, ownerKey(ownerID))
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]FileRequest, 0, len(tokens))
+	for _, token := range tokens {
+		var req FileRequest
+		if err := store.GetJSON(ctx, r.store, requestKey(token), &req); err != nil {
+			// Lien expiré : on nettoie l'index
+			if errors.Is(err, store.ErrNotFound) {
+				r.store.SRem(ctx, ownerKey(ownerID), token)
+				continue
+			}
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+// Delete supprime un lien appartenant à ownerID.
+func (r *Repository) Delete(ctx context.Context, ownerID, token string) error {
+	var req FileRequest
+	if err := store.GetJSON(ctx, r.store, requestKey(token), &req); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if req.OwnerID != ownerID {
+		return ErrNotFound
+	}
+	if err := r.store.Delete(ctx, requestKey(token)); err != nil {
+		return err
+	}
+	return r.store.SRem(ctx, ownerKey(ownerID), token)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
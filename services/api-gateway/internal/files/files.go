@@ -0,0 +1,59 @@
+// Package files interroge le service de fichiers (FILE_SERVICE_URL) sur les
+// métadonnées dont le gateway a besoin pour autoriser une requête.
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ErrNotFound est retourné quand le service de fichiers ne connaît pas le fichier.
+var ErrNotFound = errors.New("file not found")
+
+// Client appelle l'API du service de fichiers.
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient crée un client pour le service à baseURL ; http.DefaultClient si client est nil.
+func NewClient(baseURL string, client *http.Client) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{baseURL: baseURL, client: client}
+}
+
+// Owner retourne l'identifiant du propriétaire du fichier.
+func (c *Client) Owner(ctx context.Context, fileID string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/files/"+url.PathEscape(fileID), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("file service returned %d", resp.StatusCode)
+	}
+	var metadata struct {
+		OwnerID string `json:"owner_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return "", fmt.Errorf("invalid file metadata: %v", err)
+	}
+	if metadata.OwnerID == "" {
+		return "", ErrNotFound
+	}
+	return metadata.OwnerID, nil
+}
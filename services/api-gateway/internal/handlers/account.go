@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/accounts"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/audit"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/mailer"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password"`
+}
+
+// sendVerificationEmail envoie un lien de vérification de l'adresse du compte.
+func sendVerificationEmail(ctx context.Context, cfg *config.Config, deps *AuthDeps, account *accounts.Account) error {
+	token, err := deps.ActionTokens.Issue(tokens.PurposeVerifyEmail, account.UserID, account.Email, cfg.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return deps.Mailer.Send(ctx, mailer.Message{
+		To:      account.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nConfirm your email address by opening this link:\n%s\n\nThe link expires in %s.\n",
+			account.Username, actionLink(cfg, "/verify-email", token), cfg.EmailVerificationTTL),
+	})
+}
+
+// actionLink construit le lien vers la page du frontend qui soumet le token.
+func actionLink(cfg *config.Config, path, token string) string {
+	return cfg.PublicURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// VerifyEmail confirme l'adresse email avec le token reçu par email.
+func VerifyEmail(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := c.Request.Context()
+		claims, err := deps.ActionTokens.Consume(ctx, tokens.PurposeVerifyEmail, req.Token)
+		if errors.Is(err, tokens.ErrInvalidActionToken) || errors.Is(err, tokens.ErrActionTokenUsed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+			return
+		}
+
+		// L'adresse a pu changer depuis l'envoi du lien
+		account, err := deps.Accounts.MarkVerified(ctx, claims.UserID, claims.Email)
+		if errors.Is(err, accounts.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully", "email": account.Email})
+	}
+}
+
+// ResendVerificationEmail renvoie l'email de vérification à l'utilisateur connecté.
+func ResendVerificationEmail(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		account, err := deps.Accounts.Get(ctx, c.GetString("user_id"))
+		if errors.Is(err, accounts.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No email address registered"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load account"})
+			return
+		}
+		if account.Verified() {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already verified"})
+			return
+		}
+
+		if err := sendVerificationEmail(ctx, cfg, deps, account); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+	}
+}
+
+// ForgotPassword envoie un lien de réinitialisation. La réponse est la même que
+// l'adresse existe ou non, et arrive avant la recherche du compte et l'envoi :
+// son délai ne révèle pas non plus l'existence du compte.
+func ForgotPassword(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := c.Request.Context()
+		email := strings.ToLower(strings.TrimSpace(req.Email))
+		for _, key := range []string{"reset:ip:" + c.ClientIP(), "reset:email:" + email} {
+			allowed, err := deps.ResetThrottle.Allow(ctx, key)
+			if err != nil {
+				// Comme le rate limiting, continuer si le store n'est pas disponible
+				log.Printf("password reset throttle failed: %v", err)
+				continue
+			}
+			if !allowed {
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many password reset requests"})
+				return
+			}
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), resetEmailTimeout)
+			defer cancel()
+			account, err := deps.Accounts.FindByEmail(ctx, req.Email)
+			if err == nil {
+				err = sendResetEmail(ctx, cfg, deps, account)
+			}
+			if err != nil && !errors.Is(err, accounts.ErrNotFound) {
+				log.Printf("Failed to send password reset email: %v", err)
+			}
+		}()
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "If the address is registered, a reset link has been sent"})
+	}
+}
+
+// resetEmailTimeout borne la recherche du compte et l'envoi, faits après la réponse.
+const resetEmailTimeout = 30 * time.Second
+
+func sendResetEmail(ctx context.Context, cfg *config.Config, deps *AuthDeps, account *accounts.Account) error {
+	token, err := deps.ActionTokens.Issue(tokens.PurposeResetPassword, account.UserID, account.Email, cfg.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+	return deps.Mailer.Send(ctx, mailer.Message{
+		To:      account.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nReset your password by opening this link:\n%s\n\nThe link expires in %s. If you did not ask for a reset, ignore this email.\n",
+			account.Username, actionLink(cfg, "/reset-password", token), cfg.PasswordResetTTL),
+	})
+}
+
+// ResetPassword change le mot de passe avec le token reçu par email et révoque
+// toutes les sessions et clés d'API de l'utilisateur.
+func ResetPassword(cfg *config.Config, deps *AuthDeps, client ...*http.Client) gin.HandlerFunc {
+	provider := authProvider(cfg, deps, client)
+
+	return func(c *gin.Context) {
+		var req ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Vérifier le token sans le consommer : un mot de passe refusé ne doit pas le brûler
+		claims, err := deps.ActionTokens.Verify(tokens.PurposeResetPassword, req.Token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		ctx := c.Request.Context()
+		account, err := deps.Accounts.Get(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		if errs := deps.Registration.Password(account.Username, req.Password); len(errs) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": errs})
+			return
+		}
+
+		// Consommer avant l'appel au fournisseur, pour qu'un même lien ne serve pas à
+		// deux requêtes concurrentes ; il est rendu si le changement échoue
+		if _, err := deps.ActionTokens.Consume(ctx, tokens.PurposeResetPassword, req.Token); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+
+		if err := provider.ResetPassword(ctx, account.UserID, req.Password); err != nil {
+			log.Printf("Password reset of user %s failed at the auth service: %v", account.UserID, err)
+			if err := deps.ActionTokens.Release(ctx, claims); err != nil {
+				log.Printf("Failed to release password reset token: %v", err)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Auth service error"})
+			return
+		}
+
+		// Les sessions ouvertes avec l'ancien mot de passe ne sont plus valables
+		if err := deps.Denylist.RevokeUser(ctx, account.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+			return
+		}
+		if err := deps.RefreshTokens.RevokeUser(ctx, account.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh tokens"})
+			return
+		}
+		if deps.Sessions != nil {
+			if err := deps.Sessions.DeleteUser(ctx, account.UserID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sessions"})
+				return
+			}
+		}
+		// Une clé créée par quelqu'un qui connaissait l'ancien mot de passe ne doit pas survivre
+		if deps.APIKeys != nil {
+			if err := deps.APIKeys.RevokeUser(ctx, account.UserID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API keys"})
+				return
+			}
+		}
+		if deps.Lockout != nil {
+			deps.Lockout.Succeed(ctx, account.Username, "")
+		}
+		if deps.Audit != nil {
+			deps.Audit.Log(ctx, audit.Entry{
+				Action:   "password.reset",
+				Actor:    account.Username,
+				Target:   account.UserID,
+				TenantID: account.TenantID,
+				IP:       c.ClientIP(),
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+	}
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/accounts"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/lockout"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/mailer"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripFunc simule le service d'authentification pour plusieurs appels.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+	}
+}
+
+// lastMailToken lit le dernier email écrit par le mailer fichier et en extrait le token.
+func lastMailToken(t *testing.T, dir string) string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	data, err := os.ReadFile(filepath.Join(dir, entries[len(entries)-1].Name()))
+	require.NoError(t, err)
+	match := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(string(data))
+	require.Len(t, match, 2)
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+	return token
+}
+
+func TestEmailVerificationAndPasswordReset(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mailDir := t.TempDir()
+	cfg := &config.Config{
+		AuthServiceURL:       "http://auth",
+		JWT_SECRET:           "test-secret",
+		PublicURL:            "https://app.example.com",
+		EmailVerificationTTL: time.Hour,
+		PasswordResetTTL:     time.Hour,
+	}
+	st := store.NewMemory()
+	deps := newTestAuthDeps(cfg)
+	deps.Registration = &validation.Rules{PasswordMinLength: 10}
+	deps.Accounts = accounts.NewRepository(st)
+	deps.ActionTokens = tokens.NewActionTokens(st, cfg.JWT_SECRET)
+	deps.Mailer = mailer.NewFile(mailDir, "no-reply@example.com")
+
+	var resetRequest map[string]string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/reset-password" {
+			json.NewDecoder(req.Body).Decode(&resetRequest)
+			return jsonResponse(http.StatusOK, `{"user_id": "123"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"user_id": "123", "username": "alice", "role": "user"}`), nil
+	})}
+
+	router := gin.New()
+	router.POST("/register", Register(cfg, deps, notify.Multi(), client))
+	router.POST("/verify-email", VerifyEmail(deps))
+	router.POST("/forgot-password", ForgotPassword(cfg, deps))
+	router.POST("/reset-password", ResetPassword(cfg, deps, client))
+
+	// Inscription : un email de vérification est envoyé
+	w, _ := postJSON(router, "/register", gin.H{"username": "alice", "email": "alice@example.com", "password": "Correct-Horse-42"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	verifyToken := lastMailToken(t, mailDir)
+
+	w, _ = postJSON(router, "/verify-email", gin.H{"token": verifyToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+	account, _ := deps.Accounts.Get(context.Background(), "123")
+	assert.True(t, account.Verified())
+
+	// Le lien ne sert qu'une fois
+	w, _ = postJSON(router, "/verify-email", gin.H{"token": verifyToken})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// L'adresse est déjà utilisée
+	w, _ = postJSON(router, "/register", gin.H{"username": "alice2", "email": "Alice@example.com", "password": "Correct-Horse-42"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "taken")
+
+	// Mot de passe oublié : même réponse pour une adresse inconnue
+	w, _ = postJSON(router, "/forgot-password", gin.H{"email": "nobody@example.com"})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	w, _ = postJSON(router, "/forgot-password", gin.H{"email": "alice@example.com"})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	// L'email part après la réponse
+	require.Eventually(t, func() bool {
+		entries, _ := os.ReadDir(mailDir)
+		return len(entries) == 2
+	}, time.Second, 10*time.Millisecond)
+	resetToken := lastMailToken(t, mailDir)
+	assert.NotEqual(t, verifyToken, resetToken)
+
+	// Une session ouverte avant la réinitialisation
+	refreshToken, _ := deps.RefreshTokens.Issue(context.Background(), tokens.Subject{UserID: "123", Username: "alice"})
+
+	// Un mot de passe refusé ne consomme pas le token
+	w, _ = postJSON(router, "/reset-password", gin.H{"token": resetToken, "password": "short"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "too_short")
+
+	w, _ = postJSON(router, "/reset-password", gin.H{"token": resetToken, "password": "New-Password-2024"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "123", resetRequest["user_id"])
+
+	// Les sessions existantes sont invalidées
+	_, _, err := deps.RefreshTokens.Rotate(context.Background(), refreshToken)
+	assert.Error(t, err)
+	revoked, _ := deps.Denylist.IsRevoked(context.Background(), "jti", "123", time.Now().Add(-time.Second))
+	assert.True(t, revoked)
+
+	// Le lien de réinitialisation ne sert qu'une fois
+	w, _ = postJSON(router, "/reset-password", gin.H{"token": resetToken, "password": "Other-Password-2024"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestForgotPasswordThrottle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWT_SECRET: "test-secret", PasswordResetTTL: time.Hour}
+	st := store.NewMemory()
+	deps := newTestAuthDeps(cfg)
+	deps.Accounts = accounts.NewRepository(st)
+	deps.ActionTokens = tokens.NewActionTokens(st, cfg.JWT_SECRET)
+	deps.Mailer = mailer.NewFile(t.TempDir(), "no-reply@example.com")
+	deps.ResetThrottle = lockout.NewThrottle(st, 2, time.Hour)
+
+	router := gin.New()
+	router.POST("/forgot-password", ForgotPassword(cfg, deps))
+
+	for i := 0; i < 2; i++ {
+		w, _ := postJSON(router, "/forgot-password", gin.H{"email": "alice@example.com"})
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	}
+	// Limite par adresse, quelle que soit la casse
+	w, _ := postJSON(router, "/forgot-password", gin.H{"email": "Alice@example.com"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	// La même IP est aussi limitée pour les autres adresses
+	w, _ = postJSON(router, "/forgot-password", gin.H{"email": "bob@example.com"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestResetPasswordReleasesTokenOnError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{AuthServiceURL: "http://auth", JWT_SECRET: "test-secret"}
+	st := store.NewMemory()
+	deps := newTestAuthDeps(cfg)
+	deps.Registration = &validation.Rules{PasswordMinLength: 10}
+	deps.Accounts = accounts.NewRepository(st)
+	deps.ActionTokens = tokens.NewActionTokens(st, cfg.JWT_SECRET)
+	ctx := context.Background()
+	require.NoError(t, deps.Accounts.Create(ctx, &accounts.Account{UserID: "123", Username: "alice", Email: "alice@example.com"}))
+	resetToken, _ := deps.ActionTokens.Issue(tokens.PurposeResetPassword, "123", "alice@example.com", time.Hour)
+
+	available := false
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if !available {
+			return jsonResponse(http.StatusServiceUnavailable, `{"error": "unavailable"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"user_id": "123"}`), nil
+	})}
+	router := gin.New()
+	router.POST("/reset-password", ResetPassword(cfg, deps, client))
+
+	// Le fournisseur échoue : le lien reste utilisable
+	w, _ := postJSON(router, "/reset-password", gin.H{"token": resetToken, "password": "New-Password-2024"})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	available = true
+	w, _ = postJSON(router, "/reset-password", gin.H{"token": resetToken, "password": "New-Password-2024"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
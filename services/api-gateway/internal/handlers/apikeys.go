@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/apikeys"
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Scopes    []string `json:"scopes" binding:"required,min=1"`
+	ExpiresIn int64    `json:"expires_in"` // en secondes, 0 = pas d'expiration
+}
+
+// CreateAPIKey crée une clé d'API. La clé en clair n'est retournée qu'ici.
+// Une clé ne peut pas créer d'autres clés : il faut une session utilisateur.
+func CreateAPIKey(repo *apikeys.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("api_key_id") != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot create API keys"})
+			return
+		}
+
+		var req CreateAPIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.ExpiresIn < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in must be positive"})
+			return
+		}
+
+		key := &apikeys.Key{
+			UserID:   c.GetString("user_id"),
+			Username: c.GetString("username"),
+			Role:     c.GetString("role"),
+			TenantID: c.GetString("tenant_id"),
+			Name:     req.Name,
+			Scopes:   req.Scopes,
+		}
+		if req.ExpiresIn > 0 {
+			expiresAt := time.Now().UTC().Add(time.Duration(req.ExpiresIn) * time.Second)
+			key.ExpiresAt = &expiresAt
+		}
+
+		plaintext, err := repo.Create(c.Request.Context(), key)
+		if errors.Is(err, apikeys.ErrUnknownScope) || errors.Is(err, apikeys.ErrScopeNotAllowed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "scopes": apikeys.Scopes})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"api_key": key, "key": plaintext})
+	}
+}
+
+// ListAPIKeys liste les clés de l'utilisateur, sans leur valeur.
+func ListAPIKeys(repo *apikeys.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, err := repo.List(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+	}
+}
+
+// RevokeAPIKey révoque une clé de l'utilisateur.
+func RevokeAPIKey(repo *apikeys.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := repo.Revoke(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+		if errors.Is(err, apikeys.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+	}
+}
@@ -2,25 +2,80 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/accounts"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/apikeys"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/audit"
 	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/lockout"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/mailer"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/mfa"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/oidc"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/sessions"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/users"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/validation"
 )
 
+// AuthDeps regroupe les services partagés par les handlers d'authentification.
+type AuthDeps struct {
+	Keys          *tokens.KeySet
+	RefreshTokens *tokens.RefreshStore
+	Denylist      *tokens.Denylist
+	OIDC          *oidc.Registry
+	Tenants       *tenants.Repository
+	MFA           *mfa.Service
+	Lockout       *lockout.Guard
+	Audit         *audit.Log
+	Registration  *validation.Rules
+	Accounts      *accounts.Repository
+	ActionTokens  *tokens.ActionTokens
+	Mailer        mailer.Mailer
+	APIKeys       *apikeys.Repository
+	Sessions      *sessions.Repository
+	ResetThrottle *lockout.Throttle
+	// Claims fixe l'émetteur, l'audience et la tolérance d'horloge des tokens d'accès
+	Claims tokens.ClaimsConfig
+	// Provider vérifie les identifiants ; nil = service distant AUTH_SERVICE_URL
+	Provider AuthProvider
+}
+
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// Cookie demande une session navigateur : tokens en cookies HttpOnly plutôt que dans la réponse
+	Cookie bool `json:"cookie,omitempty"`
 }
 
+// RegisterRequest est validé par validation.Rules, qui retourne des erreurs par champ.
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// RefreshRequest est optionnel pour une session navigateur : le refresh token vient alors du cookie.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	// All déconnecte l'utilisateur de tous ses appareils
+	All bool `json:"all,omitempty"`
 }
 
 type AuthResponse struct {
@@ -28,14 +83,12 @@ type AuthResponse struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	TenantID string `json:"tenant_id"`
 }
 
 // Login gère la connexion de l'utilisateur.
-func Login(cfg *config.Config, client ...*http.Client) gin.HandlerFunc {
-	httpClient := http.DefaultClient
-	if len(client) > 0 {
-		httpClient = client[0]
-	}
+func Login(cfg *config.Config, deps *AuthDeps, client ...*http.Client) gin.HandlerFunc {
+	provider := authProvider(cfg, deps, client)
 
 	return func(c *gin.Context) {
 		var req LoginRequest
@@ -44,35 +97,146 @@ func Login(cfg *config.Config, client ...*http.Client) gin.HandlerFunc {
 			return
 		}
 
-		// Appeler le service d'authentification
-		authResp, err := callAuthService(httpClient, cfg.AuthServiceURL+"/login", req)
+		ctx := c.Request.Context()
+		if !checkLoginAllowed(c, deps, req.Username) {
+			return
+		}
+
+		// Vérifier les identifiants auprès du fournisseur
+		authResp, err := provider.Login(ctx, req.Username, req.Password)
+		if credentialsRejected(err) {
+			// Message générique : ne pas révéler si l'utilisateur existe
+			recordLoginFailure(c, deps, req.Username, "Invalid username or password")
+			return
+		}
+		if err != nil {
+			// Ni succès ni échec : la tentative réservée est rendue
+			releaseLoginAttempt(c, deps, req.Username)
+			log.Printf("Login for %s failed at the auth service: %v", req.Username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Auth service error"})
+			return
+		}
+
+		subject := tokens.Subject{
+			UserID:   authResp.UserID,
+			Username: authResp.Username,
+			Role:     authResp.Role,
+			TenantID: authResp.TenantID,
+		}
+		err = syncMembership(ctx, cfg, deps, &subject)
+		if errors.Is(err, tenants.ErrRemoved) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "User has been removed from the tenant"})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Auth service error: " + err.Error()})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tenant membership"})
 			return
 		}
 
-		// Générer un token JWT
-		token, err := generateJWT(authResp.UserID, authResp.Username, authResp.Role, cfg.JWT_SECRET)
+		// Second facteur : le mot de passe seul ne donne qu'un challenge, les
+		// compteurs d'échecs ne sont remis à zéro qu'une fois le code vérifié
+		if startMFAChallenge(c, cfg, deps, subject) {
+			releaseLoginAttempt(c, deps, req.Username)
+			return
+		}
+		recordLoginSuccess(c, deps, req.Username)
+
+		// Générer le token d'accès et le refresh token
+		resp, err := issueTokens(c, cfg, deps, subject)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"token":    token,
-			"user_id":  authResp.UserID,
-			"username": authResp.Username,
-			"role":     authResp.Role,
-		})
+		respondWithTokens(c, cfg, resp, req.Cookie)
 	}
 }
 
-// Register gère l'inscription d'un nouvel utilisateur.
-func Register(cfg *config.Config, client ...*http.Client) gin.HandlerFunc {
-	httpClient := http.DefaultClient
-	if len(client) > 0 {
-		httpClient = client[0]
+// Refresh échange un refresh token contre une nouvelle paire de tokens.
+// Le refresh token présenté est consommé ; le réutiliser révoque toute sa famille.
+func Refresh(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		fromCookie := false
+		if req.RefreshToken == "" {
+			req.RefreshToken, _ = c.Cookie(cfg.RefreshTokenCookie)
+			fromCookie = req.RefreshToken != ""
+		}
+		if req.RefreshToken == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+			return
+		}
+		// Le cookie est envoyé automatiquement : exiger le double submit CSRF, avec un
+		// token émis pour le titulaire du refresh token, avant de consommer ce dernier
+		ctx := c.Request.Context()
+		csrfToken := c.GetHeader("X-CSRF-Token")
+		if fromCookie {
+			holder, err := deps.RefreshTokens.Lookup(ctx, req.RefreshToken)
+			if errors.Is(err, tokens.ErrInvalidRefreshToken) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+				return
+			}
+			cookie, _ := c.Cookie(cfg.CSRFCookie)
+			if csrfToken == "" || csrfToken != cookie || !tokens.VerifyCSRFToken(cfg.JWT_SECRET, holder.UserID, csrfToken) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden", "reason": "csrf token missing or invalid"})
+				return
+			}
+		}
+
+		refreshToken, subject, err := deps.RefreshTokens.Rotate(ctx, req.RefreshToken)
+		if errors.Is(err, tokens.ErrInvalidRefreshToken) || errors.Is(err, tokens.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+			return
+		}
+
+		// Le rôle et l'appartenance au tenant peuvent avoir changé depuis la connexion
+		err = applyMembership(ctx, cfg, deps, subject)
+		if errors.Is(err, tenants.ErrRemoved) {
+			deps.RefreshTokens.Revoke(ctx, refreshToken)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+			return
+		}
+
+		token, err := generateJWT(deps, *subject, cfg.AccessTokenTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		if deps.Sessions != nil && subject.SessionID != "" {
+			deps.Sessions.Touch(ctx, subject.UserID, subject.SessionID, c.ClientIP())
+		}
+
+		resp := tokenResponse(cfg, token, refreshToken, *subject)
+		if !fromCookie {
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+		respondWithSession(c, cfg, resp, csrfToken)
 	}
+}
+
+// Register gère l'inscription d'un nouvel utilisateur. Un email de vérification
+// est envoyé à l'adresse indiquée.
+func Register(cfg *config.Config, deps *AuthDeps, notifier notify.Notifier, client ...*http.Client) gin.HandlerFunc {
+	provider := authProvider(cfg, deps, client)
 
 	return func(c *gin.Context) {
 		var req RegisterRequest
@@ -80,13 +244,55 @@ func Register(cfg *config.Config, client ...*http.Client) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return // Ajout du return manquant
 		}
+		errs := deps.Registration.Registration(req.Username, req.Email, req.Password)
+		ctx := c.Request.Context()
+		if len(errs) == 0 {
+			if _, err := deps.Accounts.FindByEmail(ctx, req.Email); err == nil {
+				errs = validation.Errors{{Field: "email", Code: "taken", Message: "Email address is already registered"}}
+			} else if !errors.Is(err, accounts.ErrNotFound) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email"})
+				return
+			}
+		}
+		if len(errs) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": errs})
+			return
+		}
 
-		// Appeler le service d'authentification
-		authResp, err := callAuthService(httpClient, cfg.AuthServiceURL+"/register", req)
+		// Créer le compte auprès du fournisseur
+		authResp, err := provider.Register(ctx, req)
+		if errors.Is(err, users.ErrUsernameTaken) {
+			errs = validation.Errors{{Field: "username", Code: "taken", Message: "Username is already taken"}}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": errs})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Auth service error: " + err.Error()})
+			log.Printf("Registration of %s failed at the auth service: %v", req.Username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Auth service error"})
+			return
+		}
+
+		account := &accounts.Account{
+			UserID:   authResp.UserID,
+			Username: authResp.Username,
+			TenantID: tenants.Resolve(authResp.TenantID, cfg.DefaultTenant),
+			Email:    req.Email,
+		}
+		if err := deps.Accounts.Create(ctx, account); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account"})
 			return
 		}
+		// L'utilisateur peut redemander l'email de vérification : un échec d'envoi ne bloque pas l'inscription
+		if err := sendVerificationEmail(ctx, cfg, deps, account); err != nil {
+			log.Printf("Failed to send verification email to user %s: %v", account.UserID, err)
+		}
+
+		notifier.Notify(ctx, notify.Event{
+			Type:     "user.registered",
+			UserID:   authResp.UserID,
+			TenantID: account.TenantID,
+			Data:     map[string]interface{}{"username": authResp.Username},
+		})
 
 		c.JSON(http.StatusCreated, gin.H{
 			"message": "User created successfully",
@@ -95,31 +301,145 @@ func Register(cfg *config.Config, client ...*http.Client) gin.HandlerFunc {
 	}
 }
 
-// Validate valide le token JWT et retourne les informations de l'utilisateur.
-func Validate(cfg *config.Config) gin.HandlerFunc {
+// Logout révoque le token d'accès courant et sa session (ou toutes les sessions avec
+// "all") et, s'il est fourni, le refresh token associé.
+func Logout(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Le middleware Auth a déjà validé le token
-		userID := c.GetString("user_id")
-		username := c.GetString("username")
-		role := c.GetString("role")
+		var req LogoutRequest
+		// Le corps est optionnel
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		// Le support ne déconnecte pas l'utilisateur de ses appareils
+		if req.All && c.GetString("actor_id") != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Cannot log out all sessions while impersonating"})
+			return
+		}
+
+		ctx := c.Request.Context()
+		jti := c.GetString("jti")
+		expiresAt := c.GetTime("token_expires_at")
+		if jti == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Token has no jti and cannot be revoked"})
+			return
+		}
+		// Session navigateur : le refresh token est dans un cookie, à supprimer
+		cookie, cookieErr := c.Cookie(cfg.RefreshTokenCookie)
+		browserSession := cookieErr == nil || c.GetString("auth_method") == "cookie"
+		if browserSession && req.RefreshToken == "" {
+			req.RefreshToken = cookie
+		}
+		// Un utilisateur ne révoque que ses propres refresh tokens
+		if req.RefreshToken != "" {
+			subject, err := deps.RefreshTokens.Lookup(ctx, req.RefreshToken)
+			if errors.Is(err, tokens.ErrInvalidRefreshToken) {
+				req.RefreshToken = ""
+			} else if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh token"})
+				return
+			} else if subject.UserID != c.GetString("user_id") {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Refresh token belongs to another user"})
+				return
+			}
+		}
+		if err := deps.Denylist.RevokeToken(ctx, jti, expiresAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+			return
+		}
+		var err error
+		if req.All {
+			err = endAllSessions(ctx, deps, c.GetString("user_id"))
+		} else if sessionID := c.GetString("session_id"); sessionID != "" {
+			err = endSession(ctx, deps, c.GetString("user_id"), sessionID)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+
+		if browserSession {
+			clearSession(c, cfg)
+		}
+		if req.RefreshToken != "" {
+			err := deps.RefreshTokens.Revoke(ctx, req.RefreshToken)
+			if err != nil && !errors.Is(err, tokens.ErrInvalidRefreshToken) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh token"})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+	}
+}
+
+// RevokeUserTokens révoque tous les tokens d'accès, refresh tokens et clés d'API d'un utilisateur.
+// Route réservée aux admins (voir middleware.RequireRole).
+func RevokeUserTokens(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("user_id")
+		ctx := c.Request.Context()
+		if err := deps.Denylist.RevokeUser(ctx, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+			return
+		}
+		if err := deps.RefreshTokens.RevokeUser(ctx, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh tokens"})
+			return
+		}
+		if deps.Sessions != nil {
+			if err := deps.Sessions.DeleteUser(ctx, userID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sessions"})
+				return
+			}
+		}
+		if deps.APIKeys != nil {
+			if err := deps.APIKeys.RevokeUser(ctx, userID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API keys"})
+				return
+			}
+		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"valid":    true,
-			"user_id":  userID,
-			"username": username,
-			"role":     role,
+			"message": "All tokens revoked",
+			"user_id": userID,
 		})
 	}
 }
 
-// callAuthService appelle le service d'authentification externe.
-func callAuthService(client *http.Client, url string, data interface{}) (*AuthResponse, error) {
+// authServiceError est une réponse non-200 du service d'authentification.
+type authServiceError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *authServiceError) Error() string {
+	return fmt.Sprintf("auth service returned non-200 status: %s, body: %s", e.Status, e.Body)
+}
+
+// rejected indique que le service a refusé la requête (identifiants invalides...),
+// par opposition à une panne.
+func (e *authServiceError) rejected() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// callAuthService appelle le service d'authentification externe. L'identité du
+// contexte est signée par le Transport du client (voir identity.Transport).
+func callAuthService(ctx context.Context, client *http.Client, url string, data interface{}) (*AuthResponse, error) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	resp, err := client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call auth service: %v", err)
 	}
@@ -127,7 +447,7 @@ func callAuthService(client *http.Client, url string, data interface{}) (*AuthRe
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("auth service returned non-200 status: %s, body: %s", resp.Status, string(body))
+		return nil, &authServiceError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(body)}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -143,16 +463,104 @@ func callAuthService(client *http.Client, url string, data interface{}) (*AuthRe
 	return &authResp, nil
 }
 
-// generateJWT génère un token JWT pour l'utilisateur.
-func generateJWT(userID, username, role, secret string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id":  userID,
-		"username": username,
-		"role":     role,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
-		"iat":      time.Now().Unix(),
+// issueTokens ouvre une session : token d'accès et nouvelle famille de refresh tokens,
+// identifiés par le même identifiant de session. L'utilisateur devient membre de son
+// tenant à la première connexion.
+func issueTokens(c *gin.Context, cfg *config.Config, deps *AuthDeps, subject tokens.Subject) (gin.H, error) {
+	ctx := c.Request.Context()
+	if err := applyMembership(ctx, cfg, deps, &subject); err != nil {
+		return nil, err
+	}
+	sessionID, err := newJTI()
+	if err != nil {
+		return nil, err
+	}
+	subject.SessionID = sessionID
+	if deps.Sessions != nil {
+		err := deps.Sessions.Create(ctx, &sessions.Session{
+			ID:        sessionID,
+			UserID:    subject.UserID,
+			TenantID:  subject.TenantID,
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	token, err := generateJWT(deps, subject, cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := deps.RefreshTokens.Issue(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	return tokenResponse(cfg, token, refreshToken, subject), nil
+}
+
+// syncMembership est applyMembership pour une connexion, dont le rôle vient d'être
+// confirmé par le fournisseur d'identité. Le rôle admin est global : un tenant ne
+// peut ni le donner ni le retirer, le membre est donc réaligné sur le fournisseur.
+func syncMembership(ctx context.Context, cfg *config.Config, deps *AuthDeps, subject *tokens.Subject) error {
+	providerRole := subject.Role
+	if err := applyMembership(ctx, cfg, deps, subject); err != nil {
+		return err
+	}
+	if deps.Tenants == nil || (subject.Role == "admin") == (providerRole == "admin") {
+		return nil
+	}
+	member, err := deps.Tenants.AssignRole(ctx, subject.TenantID, subject.UserID, providerRole)
+	if err != nil {
+		return err
+	}
+	subject.Role = member.Role
+	return nil
+}
+
+// applyMembership rattache le sujet à son tenant et reprend le rôle qui y est attribué.
+func applyMembership(ctx context.Context, cfg *config.Config, deps *AuthDeps, subject *tokens.Subject) error {
+	subject.TenantID = tenants.Resolve(subject.TenantID, cfg.DefaultTenant)
+	if deps.Tenants == nil {
+		return nil
+	}
+	member, err := deps.Tenants.Join(ctx, subject.TenantID, subject.UserID, subject.Username, subject.Role)
+	if err != nil {
+		return err
+	}
+	subject.Role = member.Role
+	return nil
+}
+
+func tokenResponse(cfg *config.Config, token, refreshToken string, subject tokens.Subject) gin.H {
+	return gin.H{
+		"token":         token,
+		"token_type":    "Bearer",
+		"expires_in":    int64(cfg.AccessTokenTTL.Seconds()),
+		"refresh_token": refreshToken,
+		"user_id":       subject.UserID,
+		"username":      subject.Username,
+		"role":          subject.Role,
+		"tenant_id":     subject.TenantID,
+		"session_id":    subject.SessionID,
+	}
+}
+
+// generateJWT génère un token JWT de courte durée pour l'utilisateur.
+func generateJWT(deps *AuthDeps, subject tokens.Subject, ttl time.Duration) (string, error) {
+	// Identifiant unique du token, utilisé pour la révocation
+	jti, err := newJTI()
+	if err != nil {
+		return "", err
+	}
+	return deps.Keys.Sign(tokens.NewClaims(subject, jti, ttl, deps.Claims))
+}
+
+func newJTI() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
@@ -2,15 +2,24 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/accounts"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/apikeys"
 	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/validation"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,6 +33,17 @@ func (m *MockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 	return m.Response, m.Error
 }
 
+// newTestAuthDeps crée les services d'authentification avec un store en mémoire.
+func newTestAuthDeps(cfg *config.Config) *AuthDeps {
+	st := store.NewMemory()
+	return &AuthDeps{
+		Keys:          tokens.NewHMACKeySet(cfg.JWT_SECRET),
+		RefreshTokens: tokens.NewRefreshStore(st, time.Hour),
+		Denylist:      tokens.NewDenylist(st, time.Hour, time.Second),
+		Tenants:       tenants.NewRepository(st),
+	}
+}
+
 func TestLoginValidCredentials(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
@@ -52,7 +72,7 @@ func TestLoginValidCredentials(t *testing.T) {
 	}
 
 	// Utilise le mock dans le handler
-	router.POST("/login", Login(cfg, mockClient))
+	router.POST("/login", Login(cfg, newTestAuthDeps(cfg), mockClient))
 
 	// Test data
 	loginReq := LoginRequest{
@@ -95,7 +115,7 @@ func TestLoginInvalidCredentials(t *testing.T) {
 	}
 
 	// Utilise le mock dans le handler
-	router.POST("/login", Login(cfg, mockClient))
+	router.POST("/login", Login(cfg, newTestAuthDeps(cfg), mockClient))
 
 	// Test data
 	loginReq := LoginRequest{
@@ -110,9 +130,9 @@ func TestLoginInvalidCredentials(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	// Assertions
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Contains(t, w.Body.String(), "Auth service error")
+	// Assertions : message générique, sans détail du service d'authentification
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid username or password")
 }
 
 func TestLoginAuthServiceUnreachable(t *testing.T) {
@@ -130,7 +150,7 @@ func TestLoginAuthServiceUnreachable(t *testing.T) {
 	}
 
 	// Utilise le mock dans le handler
-	router.POST("/login", Login(cfg, mockClient))
+	router.POST("/login", Login(cfg, newTestAuthDeps(cfg), mockClient))
 
 	// Test data
 	loginReq := LoginRequest{
@@ -148,4 +168,99 @@ func TestLoginAuthServiceUnreachable(t *testing.T) {
 	// Assertions
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "Auth service error")
+	assert.NotContains(t, w.Body.String(), "connection refused")
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cfg := &config.Config{
+		JWT_SECRET:     "test-secret",
+		AccessTokenTTL: time.Minute,
+	}
+	deps := newTestAuthDeps(cfg)
+	router.POST("/refresh", Refresh(cfg, deps))
+
+	refreshToken, _ := deps.RefreshTokens.Issue(context.Background(), tokens.Subject{UserID: "123", Username: "testuser", Role: "user"})
+	body := fmt.Sprintf(`{"refresh_token": %q}`, refreshToken)
+
+	// Premier échange : nouvelle paire de tokens
+	req, _ := http.NewRequest("POST", "/refresh", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NotEmpty(t, resp["token"])
+	assert.NotEqual(t, refreshToken, resp["refresh_token"])
+
+	// Rejouer l'ancien refresh token est refusé
+	req, _ = http.NewRequest("POST", "/refresh", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRegisterValidationErrors(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cfg := &config.Config{AuthServiceURL: "http://localhost:8081"}
+	rules := &validation.Rules{UsernameMinLength: 3, PasswordMinLength: 10, PasswordMinClasses: 3}
+
+	// Le service d'authentification ne doit pas être appelé
+	mockClient := &http.Client{
+		Transport: &MockRoundTripper{Error: fmt.Errorf("unexpected call")},
+	}
+	deps := newTestAuthDeps(cfg)
+	deps.Registration = rules
+	deps.Accounts = accounts.NewRepository(store.NewMemory())
+	router.POST("/register", Register(cfg, deps, notify.Multi(), mockClient))
+
+	// Test
+	req, _ := http.NewRequest("POST", "/register", bytes.NewBufferString(`{"username": "a", "password": "a"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assertions : une erreur par champ, affichable dans le formulaire
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp struct {
+		Fields []validation.FieldError `json:"fields"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	fields := make(map[string]bool)
+	for _, f := range resp.Fields {
+		fields[f.Field] = true
+		assert.NotEmpty(t, f.Message)
+	}
+	assert.Equal(t, map[string]bool{"username": true, "email": true, "password": true}, fields)
+}
+
+func TestRevokeUserTokensRevokesAPIKeys(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWT_SECRET: "test-secret"}
+	deps := newTestAuthDeps(cfg)
+	deps.APIKeys = apikeys.NewRepository(store.NewMemory())
+	ctx := context.Background()
+	plaintext, err := deps.APIKeys.Create(ctx, &apikeys.Key{UserID: "123", Role: "user", Scopes: []string{"files:read"}})
+	assert.NoError(t, err)
+	router := gin.New()
+	router.POST("/admin/users/:user_id/revoke", RevokeUserTokens(deps))
+
+	req, _ := http.NewRequest("POST", "/admin/users/123/revoke", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, err = deps.APIKeys.Authenticate(ctx, plaintext)
+	assert.ErrorIs(t, err, apikeys.ErrNotFound)
+	keys, _ := deps.APIKeys.List(ctx, "123")
+	assert.Empty(t, keys)
 }
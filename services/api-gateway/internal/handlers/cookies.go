@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+)
+
+// Chemin du cookie de refresh : il n'est envoyé qu'aux routes /auth (refresh, logout)
+const refreshCookiePath = "/api/v1/auth"
+
+// respondWithTokens retourne les tokens dans la réponse, ou en cookies pour une session navigateur.
+func respondWithTokens(c *gin.Context, cfg *config.Config, resp gin.H, cookie bool) {
+	if cookie {
+		respondWithSession(c, cfg, resp, "")
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// respondWithSession pose les cookies de session (token d'accès et refresh token
+// HttpOnly, token CSRF lisible par le frontend) et retire les tokens de la réponse :
+// ils ne doivent pas être accessibles au JavaScript. Un token CSRF vide en crée un nouveau ;
+// au refresh, le token existant est conservé pour ne pas invalider les requêtes en cours.
+func respondWithSession(c *gin.Context, cfg *config.Config, resp gin.H, csrfToken string) {
+	if csrfToken == "" {
+		userID, _ := resp["user_id"].(string)
+		var err error
+		csrfToken, err = tokens.NewCSRFToken(cfg.JWT_SECRET, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+			return
+		}
+	}
+
+	accessToken, _ := resp["token"].(string)
+	refreshToken, _ := resp["refresh_token"].(string)
+	setCookie(c, cfg, cfg.AccessTokenCookie, accessToken, "/", int(cfg.AccessTokenTTL.Seconds()), true)
+	setCookie(c, cfg, cfg.RefreshTokenCookie, refreshToken, refreshCookiePath, int(cfg.RefreshTokenTTL.Seconds()), true)
+	setCookie(c, cfg, cfg.CSRFCookie, csrfToken, "/", int(cfg.RefreshTokenTTL.Seconds()), false)
+
+	delete(resp, "token")
+	delete(resp, "refresh_token")
+	resp["token_type"] = "Cookie"
+	resp["csrf_token"] = csrfToken
+	c.JSON(http.StatusOK, resp)
+}
+
+// clearSession supprime les cookies de session.
+func clearSession(c *gin.Context, cfg *config.Config) {
+	setCookie(c, cfg, cfg.AccessTokenCookie, "", "/", -1, true)
+	setCookie(c, cfg, cfg.RefreshTokenCookie, "", refreshCookiePath, -1, true)
+	setCookie(c, cfg, cfg.CSRFCookie, "", "/", -1, false)
+}
+
+func setCookie(c *gin.Context, cfg *config.Config, name, value, path string, maxAge int, httpOnly bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   cfg.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   cfg.CookieSecure,
+		HttpOnly: httpOnly,
+		SameSite: sameSite(cfg.CookieSameSite),
+	})
+}
+
+func sameSite(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
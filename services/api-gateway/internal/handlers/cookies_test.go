@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func responseCookies(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range (&http.Response{Header: w.Header()}).Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	return cookies
+}
+
+func TestLoginCookieSession(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		AuthServiceURL:     "http://auth",
+		JWT_SECRET:         "test-secret",
+		AccessTokenTTL:     time.Minute,
+		RefreshTokenTTL:    time.Hour,
+		AccessTokenCookie:  "mc_access_token",
+		RefreshTokenCookie: "mc_refresh_token",
+		CSRFCookie:         "mc_csrf",
+		CookieSecure:       true,
+		CookieSameSite:     "strict",
+	}
+	deps := newTestAuthDeps(cfg)
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// Le mode cookie n'est pas transmis au service d'authentification
+		body, _ := io.ReadAll(req.Body)
+		assert.NotContains(t, string(body), "cookie")
+		return jsonResponse(http.StatusOK, `{"user_id": "123", "username": "alice", "role": "user"}`), nil
+	})}
+	router := gin.New()
+	router.POST("/login", Login(cfg, deps, client))
+	router.POST("/refresh", Refresh(cfg, deps))
+
+	w, resp := postJSON(router, "/login", gin.H{"username": "alice", "password": "secret", "cookie": true})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Les tokens ne sont que dans des cookies HttpOnly
+	assert.Nil(t, resp["token"])
+	assert.Nil(t, resp["refresh_token"])
+	cookies := responseCookies(w)
+	access, refresh, csrf := cookies["mc_access_token"], cookies["mc_refresh_token"], cookies["mc_csrf"]
+	require.NotNil(t, access)
+	require.NotNil(t, refresh)
+	require.NotNil(t, csrf)
+	assert.True(t, access.HttpOnly)
+	assert.True(t, access.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, access.SameSite)
+	assert.True(t, refresh.HttpOnly)
+	assert.Equal(t, "/api/v1/auth", refresh.Path)
+	assert.False(t, csrf.HttpOnly)
+	assert.Equal(t, csrf.Value, resp["csrf_token"])
+
+	refreshWithCookies := func(csrfHeader string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/refresh", bytes.NewBuffer(nil))
+		req.AddCookie(refresh)
+		req.AddCookie(csrf)
+		if csrfHeader != "" {
+			req.Header.Set("X-CSRF-Token", csrfHeader)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Refresh par cookie sans token CSRF : refusé, le refresh token n'est pas consommé
+	assert.Equal(t, http.StatusForbidden, refreshWithCookies("").Code)
+
+	// Token CSRF valide mais émis pour un autre compte (cookie posé par un sous-domaine) :
+	// refusé avant la rotation, le refresh token reste utilisable
+	foreign, _ := tokens.NewCSRFToken(cfg.JWT_SECRET, "other-user")
+	req, _ := http.NewRequest("POST", "/refresh", bytes.NewBuffer(nil))
+	req.AddCookie(refresh)
+	req.AddCookie(&http.Cookie{Name: "mc_csrf", Value: foreign})
+	req.Header.Set("X-CSRF-Token", foreign)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = refreshWithCookies(csrf.Value)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Nil(t, resp["token"])
+	// Le token CSRF est conservé, les tokens sont renouvelés
+	assert.Equal(t, csrf.Value, resp["csrf_token"])
+	assert.NotEqual(t, refresh.Value, responseCookies(w)["mc_refresh_token"].Value)
+}
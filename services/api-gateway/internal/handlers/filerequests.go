@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/filerequests"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/quota"
+)
+
+// Marge laissée à l'enveloppe multipart au-delà de la taille du fichier
+const multipartOverhead = 1 << 20
+
+type CreateFileRequestRequest struct {
+	Folder       string   `json:"folder" binding:"required"`
+	Title        string   `json:"title"`
+	ExpiresIn    int64    `json:"expires_in"` // en secondes, 0 = pas d'expiration
+	MaxSize      int64    `json:"max_size"`
+	AllowedTypes []string `json:"allowed_types"`
+}
+
+// CreateFileRequest crée un lien de dépôt public vers un dossier de l'utilisateur.
+func CreateFileRequest(repo *filerequests.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateFileRequestRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.ExpiresIn < 0 || req.MaxSize < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in and max_size must be positive"})
+			return
+		}
+
+		fileRequest := &filerequests.FileRequest{
+			OwnerID:      c.GetString("user_id"),
+			TenantID:     c.GetString("tenant_id"),
+			Folder:       req.Folder,
+			Title:        req.Title,
+			MaxSize:      req.MaxSize,
+			AllowedTypes: req.AllowedTypes,
+		}
+		if req.ExpiresIn > 0 {
+			expiresAt := time.Now().UTC().Add(time.Duration(req.ExpiresIn) * time.Second)
+			fileRequest.ExpiresAt = &expiresAt
+		}
+
+		if err := repo.Create(c.Request.Context(), fileRequest); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create file request"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"file_request": fileRequest,
+			"upload_url":   "/api/v1/public/file-requests/" + fileRequest.Token + "/upload",
+		})
+	}
+}
+
+// ListFileRequests liste les liens de dépôt de l'utilisateur.
+func ListFileRequests(repo *filerequests.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requests, err := repo.ListByOwner(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list file requests"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"file_requests": requests})
+	}
+}
+
+// DeleteFileRequest désactive un lien de dépôt.
+func DeleteFileRequest(repo *filerequests.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := repo.Delete(c.Request.Context(), c.GetString("user_id"), c.Param("token"))
+		if errors.Is(err, filerequests.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File request not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file request"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "File request deleted successfully"})
+	}
+}
+
+// GetPublicFileRequest décrit un lien de dépôt sans révéler le dossier ni son propriétaire.
+func GetPublicFileRequest(repo *filerequests.Repository, notifier notify.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileRequest, ok := loadPublicFileRequest(c, repo)
+		if !ok {
+			return
+		}
+
+		notifier.Notify(c.Request.Context(), notify.Event{
+			Type:     "share.accessed",
+			UserID:   fileRequest.OwnerID,
+			TenantID: fileRequest.TenantID,
+			Data: map[string]interface{}{
+				"token":     fileRequest.Token,
+				"client_ip": c.ClientIP(),
+			},
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"title":         fileRequest.Title,
+			"max_size":      fileRequest.MaxSize,
+			"allowed_types": fileRequest.AllowedTypes,
+			"expires_at":    fileRequest.ExpiresAt,
+		})
+	}
+}
+
+// UploadToFileRequest reçoit un fichier déposé par une personne sans compte.
+// Le fichier est compté dans le quota du propriétaire, qui est notifié.
+func UploadToFileRequest(cfg *config.Config, repo *filerequests.Repository, quotas *quota.Tracker, notifier notify.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileRequest, ok := loadPublicFileRequest(c, repo)
+		if !ok {
+			return
+		}
+
+		maxSize := int64(cfg.MaxUploadSize)
+		if fileRequest.MaxSize > 0 && (maxSize <= 0 || fileRequest.MaxSize < maxSize) {
+			maxSize = fileRequest.MaxSize
+		}
+		if maxSize > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+		}
+
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			if bodyTooLarge(err) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+			return
+		}
+		defer file.Close()
+
+		if maxSize > 0 && header.Size > maxSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large", "max_size": maxSize})
+			return
+		}
+		if !fileRequest.Allows(header.Filename, header.Header.Get("Content-Type")) {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File type not allowed"})
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := quotas.Reserve(ctx, fileRequest.TenantID, fileRequest.OwnerID, header.Size); err != nil {
+			if errors.Is(err, quota.ErrQuotaExceeded) {
+				c.JSON(http.StatusInsufficientStorage, gin.H{"error": "Recipient storage quota exceeded"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check quota"})
+			return
+		}
+
+		// TODO: Appeler le service de fichiers avec fileRequest.Folder comme destination
+		notifier.Notify(ctx, notify.Event{
+			Type:     "file_request.uploaded",
+			UserID:   fileRequest.OwnerID,
+			TenantID: fileRequest.TenantID,
+			Data: map[string]interface{}{
+				"token":    fileRequest.Token,
+				"folder":   fileRequest.Folder,
+				"filename": header.Filename,
+				"size":     header.Size,
+			},
+		})
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":  "File uploaded successfully",
+			"filename": header.Filename,
+			"size":     header.Size,
+		})
+	}
+}
+
+// loadPublicFileRequest charge le lien du paramètre :token et répond en cas d'erreur.
+func loadPublicFileRequest(c *gin.Context, repo *filerequests.Repository) (*filerequests.FileRequest, bool) {
+	fileRequest, err := repo.Get(c.Request.Context(), c.Param("token"))
+	switch {
+	case errors.Is(err, filerequests.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "File request not found"})
+		return nil, false
+	case errors.Is(err, filerequests.ErrExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "File request expired"})
+		return nil, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load file request"})
+		return nil, false
+	}
+	return fileRequest, true
+}
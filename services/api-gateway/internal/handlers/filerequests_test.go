@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/filerequests"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/quota"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupFileRequestRouter(quotaLimit int64) (*gin.Engine, *quota.Tracker, *notify.Inbox) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	st := store.NewMemory()
+	repo := filerequests.NewRepository(st)
+	quotas := quota.New(st, quotaLimit)
+	inbox := notify.NewInbox(st)
+	cfg := &config.Config{MaxUploadSize: 1 << 20}
+
+	owner := router.Group("/")
+	owner.Use(func(c *gin.Context) {
+		c.Set("user_id", "owner-1")
+		c.Set("tenant_id", "tenant-1")
+		c.Next()
+	})
+	owner.POST("/file-requests", CreateFileRequest(repo))
+	router.GET("/public/file-requests/:token", GetPublicFileRequest(repo, inbox))
+	router.POST("/public/file-requests/:token/upload", UploadToFileRequest(cfg, repo, quotas, inbox))
+
+	return router, quotas, inbox
+}
+
+func createFileRequest(t *testing.T, router *gin.Engine, body string) string {
+	req, _ := http.NewRequest("POST", "/file-requests", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp struct {
+		FileRequest filerequests.FileRequest `json:"file_request"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.FileRequest.Token
+}
+
+func uploadMultipart(router *gin.Engine, token, filename, contentType string, content []byte) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	h.Set("Content-Type", contentType)
+	part, _ := writer.CreatePart(h)
+	part.Write(content)
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/public/file-requests/"+token+"/upload", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestFileRequestUpload(t *testing.T) {
+	router, quotas, inbox := setupFileRequestRouter(0)
+	token := createFileRequest(t, router, `{"folder": "/clients/acme", "expires_in": 3600, "allowed_types": [".pdf"]}`)
+
+	// Les infos publiques ne révèlent pas le dossier
+	req, _ := http.NewRequest("GET", "/public/file-requests/"+token, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "acme")
+
+	w = uploadMultipart(router, token, "invoice.pdf", "application/pdf", []byte("%PDF-1.4"))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	used, _, _ := quotas.Usage(req.Context(), "tenant-1", "owner-1")
+	assert.Equal(t, int64(8), used)
+
+	events, _ := inbox.List(req.Context(), "owner-1")
+	assert.Len(t, events, 2)
+	assert.Equal(t, "file_request.uploaded", events[0].Type)
+	assert.Equal(t, "share.accessed", events[1].Type)
+}
+
+func TestFileRequestRejectsType(t *testing.T) {
+	router, _, _ := setupFileRequestRouter(0)
+	token := createFileRequest(t, router, `{"folder": "/in", "allowed_types": ["image/*"]}`)
+
+	w := uploadMultipart(router, token, "script.sh", "text/x-shellscript", []byte("echo"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestFileRequestRejectsSize(t *testing.T) {
+	router, _, _ := setupFileRequestRouter(0)
+	token := createFileRequest(t, router, `{"folder": "/in", "max_size": 4}`)
+
+	w := uploadMultipart(router, token, "big.txt", "text/plain", []byte("too large"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestFileRequestOwnerQuota(t *testing.T) {
+	router, _, _ := setupFileRequestRouter(4)
+	token := createFileRequest(t, router, `{"folder": "/in"}`)
+
+	w := uploadMultipart(router, token, "data.txt", "text/plain", []byte("over quota"))
+	assert.Equal(t, http.StatusInsufficientStorage, w.Code)
+}
+
+func TestFileRequestUnknownToken(t *testing.T) {
+	router, _, _ := setupFileRequestRouter(0)
+
+	w := uploadMultipart(router, "unknown", "data.txt", "text/plain", []byte("data"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
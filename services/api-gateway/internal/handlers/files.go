@@ -1,33 +1,65 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/middleware"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/quota"
 )
 
-func UploadFile(cfg *config.Config) gin.HandlerFunc {
+func UploadFile(cfg *config.Config, quotas *quota.Tracker, notifier notify.Notifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Récupérer l'utilisateur depuis le contexte
-		userID := c.GetString("user_id")
-		username := c.GetString("username")
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		userID, tenantID := principal.UserID, principal.TenantID
 
 		// Récupérer le fichier
 		file, header, err := c.Request.FormFile("file")
 		if err != nil {
+			// Corps décompressé par Decompress au-delà de la taille maximale
+			if bodyTooLarge(err) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large"})
+				return
+			}
 			c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
 			return
 		}
 		defer file.Close()
 
+		// Comptabiliser le fichier dans le quota de l'utilisateur
+		if err := quotas.Reserve(c.Request.Context(), tenantID, userID, header.Size); err != nil {
+			if errors.Is(err, quota.ErrQuotaExceeded) {
+				c.JSON(http.StatusInsufficientStorage, gin.H{"error": "Storage quota exceeded"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check quota"})
+			return
+		}
+
 		// TODO: Appeler le service de fichiers
 		// Pour l'instant, on simule
+		notifier.Notify(c.Request.Context(), notify.Event{
+			Type:     "file.uploaded",
+			UserID:   userID,
+			TenantID: tenantID,
+			Data: map[string]interface{}{
+				"filename": header.Filename,
+				"size":     header.Size,
+			},
+		})
+
 		c.JSON(http.StatusOK, gin.H{
 			"message":     "File uploaded successfully",
 			"filename":    header.Filename,
 			"size":        header.Size,
-			"uploaded_by": username,
+			"uploaded_by": principal.Username,
 			"user_id":     userID,
 		})
 	}
@@ -35,30 +67,60 @@ func UploadFile(cfg *config.Config) gin.HandlerFunc {
 
 func DownloadFile(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
 		fileID := c.Param("id")
-		userID := c.GetString("user_id")
 
 		// TODO: Appeler le service de fichiers
 		// Pour l'instant, on simule
 		c.JSON(http.StatusOK, gin.H{
 			"message": "File download initiated",
 			"file_id": fileID,
-			"user_id": userID,
+			"user_id": principal.UserID,
 		})
 	}
 }
 
-func DeleteFile(cfg *config.Config) gin.HandlerFunc {
+func DeleteFile(cfg *config.Config, notifier notify.Notifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		principal, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
 		fileID := c.Param("id")
-		userID := c.GetString("user_id")
 
 		// TODO: Appeler le service de fichiers
 		// Pour l'instant, on simule
+		notifier.Notify(c.Request.Context(), notify.Event{
+			Type:     "file.deleted",
+			UserID:   principal.UserID,
+			TenantID: principal.TenantID,
+			Data:     map[string]interface{}{"file_id": fileID},
+		})
+
 		c.JSON(http.StatusOK, gin.H{
 			"message":    "File deleted successfully",
 			"file_id":    fileID,
-			"deleted_by": userID,
+			"deleted_by": principal.UserID,
 		})
 	}
 }
+
+// currentPrincipal retourne l'utilisateur authentifié par middleware.Auth. Sans
+// identité, la requête est refusée plutôt que traitée avec un user_id vide.
+// bodyTooLarge indique si la lecture du corps a dépassé la limite d'un http.MaxBytesReader.
+func bodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+func currentPrincipal(c *gin.Context) (*middleware.Principal, bool) {
+	principal, ok := middleware.CurrentPrincipal(c)
+	if !ok || principal.UserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	}
+	return principal, true
+}
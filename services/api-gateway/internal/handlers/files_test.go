@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/middleware"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/quota"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadFileDecompressedTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{MaxUploadSize: 1 << 10}
+	router := gin.New()
+	router.POST("/files/upload", func(c *gin.Context) {
+		c.Set("principal", &middleware.Principal{UserID: "123", TenantID: "acme"})
+	}, middleware.Decompress(int64(cfg.MaxUploadSize)), UploadFile(cfg, quota.New(store.NewMemory(), 0), nil))
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, _ := writer.CreateFormFile("file", "big.txt")
+	part.Write(bytes.Repeat([]byte("a"), 64<<10))
+	writer.Close()
+
+	// Le corps compressé est petit, mais dépasse la limite une fois décompressé
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	gz.Write(form.Bytes())
+	gz.Close()
+
+	req, _ := http.NewRequest("POST", "/files/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+)
+
+// ForwardAuth protège les applications derrière l'ingress (NGINX auth_request,
+// Traefik ForwardAuth). Le token vient du header Authorization ou du cookie
+// d'accès. En cas de succès, l'identité est transmise dans les headers X-User-* ;
+// sinon la réponse est 401 et, pour un navigateur, X-Auth-Redirect indique la page
+// de connexion (ex. NGINX : auth_request_set + error_page 401).
+func ForwardAuth(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := forwardedToken(c, cfg.AccessTokenCookie)
+		if token == "" {
+			forwardUnauthorized(c, cfg, "Authentication required")
+			return
+		}
+
+		claims, err := introspectJWT(c, cfg, deps, token)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token revocation"})
+			return
+		}
+		if claims == nil {
+			forwardUnauthorized(c, cfg, "Invalid token")
+			return
+		}
+
+		c.Header("X-User-Id", fmt.Sprint(claims["sub"]))
+		c.Header("X-User-Name", fmt.Sprint(claims["username"]))
+		c.Header("X-User-Role", fmt.Sprint(claims["role"]))
+		c.Header("X-Tenant-Id", fmt.Sprint(claims["tenant_id"]))
+		if act, ok := claims["act"].(*tokens.Actor); ok && act != nil {
+			c.Header("X-Actor-Id", act.UserID)
+		}
+		c.Status(http.StatusOK)
+	}
+}
+
+func forwardedToken(c *gin.Context, cookieName string) string {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return token
+	}
+	if cookieName == "" {
+		return ""
+	}
+	token, _ := c.Cookie(cookieName)
+	return token
+}
+
+func forwardUnauthorized(c *gin.Context, cfg *config.Config, message string) {
+	resp := gin.H{"error": message}
+	if strings.Contains(c.GetHeader("Accept"), "text/html") {
+		redirect := loginRedirect(cfg, originalURL(c))
+		c.Header("X-Auth-Redirect", redirect)
+		resp["redirect_url"] = redirect
+	}
+	c.JSON(http.StatusUnauthorized, resp)
+}
+
+// loginRedirect construit l'URL de la page de connexion, avec l'adresse d'origine en paramètre rd.
+func loginRedirect(cfg *config.Config, original string) string {
+	loginURL := cfg.ForwardAuthLoginURL
+	if loginURL == "" {
+		loginURL = cfg.PublicURL + "/login"
+	}
+	if original == "" {
+		return loginURL
+	}
+	separator := "?"
+	if strings.Contains(loginURL, "?") {
+		separator = "&"
+	}
+	return loginURL + separator + "rd=" + url.QueryEscape(original)
+}
+
+// originalURL reconstruit l'URL demandée à l'ingress : X-Original-URL (NGINX) ou
+// X-Forwarded-Proto/Host/Uri (Traefik).
+func originalURL(c *gin.Context) string {
+	if original := c.GetHeader("X-Original-URL"); original != "" {
+		return original
+	}
+	host := c.GetHeader("X-Forwarded-Host")
+	if host == "" {
+		return ""
+	}
+	proto := c.GetHeader("X-Forwarded-Proto")
+	if proto == "" {
+		proto = "https"
+	}
+	return proto + "://" + host + c.GetHeader("X-Forwarded-Uri")
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwardAuth(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		JWT_SECRET:        "test-secret",
+		PublicURL:         "https://cloud.example.com",
+		AccessTokenCookie: "mc_access_token",
+	}
+	deps := newTestAuthDeps(cfg)
+	router := gin.New()
+	router.GET("/forward", ForwardAuth(cfg, deps))
+
+	token, _ := generateJWT(deps, tokens.Subject{UserID: "123", Username: "alice", Role: "user", TenantID: "tenant-1"}, time.Minute)
+
+	// Token dans le header Authorization
+	req, _ := http.NewRequest("GET", "/forward", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "123", w.Header().Get("X-User-Id"))
+	assert.Equal(t, "alice", w.Header().Get("X-User-Name"))
+	assert.Equal(t, "user", w.Header().Get("X-User-Role"))
+	assert.Empty(t, w.Header().Get("X-Actor-Id"))
+
+	// Token d'impersonation : l'admin est transmis dans X-Actor-Id
+	impersonation, _ := generateJWT(deps, tokens.Subject{UserID: "123", Username: "alice", Role: "user", TenantID: "tenant-1", Actor: &tokens.Actor{UserID: "admin-1", Username: "root"}}, time.Minute)
+	req, _ = http.NewRequest("GET", "/forward", nil)
+	req.Header.Set("Authorization", "Bearer "+impersonation)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "123", w.Header().Get("X-User-Id"))
+	assert.Equal(t, "admin-1", w.Header().Get("X-Actor-Id"))
+
+	// Token dans le cookie
+	req, _ = http.NewRequest("GET", "/forward", nil)
+	req.AddCookie(&http.Cookie{Name: "mc_access_token", Value: token})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Appel d'API sans token : pas de redirection
+	req, _ = http.NewRequest("GET", "/forward", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("X-Auth-Redirect"))
+
+	// Navigateur avec un token invalide : redirection vers la connexion
+	req, _ = http.NewRequest("GET", "/forward", nil)
+	req.Header.Set("Authorization", "Bearer invalid")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "app.example.com")
+	req.Header.Set("X-Forwarded-Uri", "/dashboard")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "https://cloud.example.com/login?rd=https%3A%2F%2Fapp.example.com%2Fdashboard", w.Header().Get("X-Auth-Redirect"))
+	assert.Contains(t, w.Body.String(), "redirect_url")
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/audit"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+)
+
+// ImpersonateRequest est optionnel ; le motif est conservé dans le journal d'audit.
+type ImpersonateRequest struct {
+	Reason   string `json:"reason"`
+	TenantID string `json:"tenant_id"`
+}
+
+// Impersonate délivre à un admin un token de courte durée au nom d'un utilisateur,
+// pour voir ce qu'il voit. Le token porte l'admin dans le claim "act" ; il n'a pas
+// de refresh token et ne peut pas viser un autre admin.
+func Impersonate(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ImpersonateRequest
+		// Le corps est optionnel
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if c.GetString("actor_id") != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Cannot impersonate while impersonating"})
+			return
+		}
+		targetID := c.Param("user_id")
+		if targetID == c.GetString("user_id") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot impersonate yourself"})
+			return
+		}
+
+		ctx := c.Request.Context()
+		tenantID := req.TenantID
+		if tenantID == "" && deps.Accounts != nil {
+			if account, err := deps.Accounts.Get(ctx, targetID); err == nil {
+				tenantID = account.TenantID
+			}
+		}
+		tenantID = tenants.Resolve(tenantID, c.GetString("tenant_id"))
+
+		member, err := deps.Tenants.Get(ctx, tenantID, targetID)
+		if errors.Is(err, tenants.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+			return
+		}
+		if member.RemovedAt != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "User has been removed from the tenant"})
+			return
+		}
+		if member.Role == "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Cannot impersonate an admin"})
+			return
+		}
+
+		subject := tokens.Subject{
+			UserID:   member.UserID,
+			Username: member.Username,
+			Role:     member.Role,
+			TenantID: member.TenantID,
+			Actor:    &tokens.Actor{UserID: c.GetString("user_id"), Username: c.GetString("username")},
+		}
+		token, err := generateJWT(deps, subject, cfg.ImpersonationTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		expiresAt := time.Now().Add(cfg.ImpersonationTTL).UTC()
+
+		if deps.Audit != nil {
+			deps.Audit.Log(ctx, audit.Entry{
+				Action:   "impersonation.started",
+				Actor:    subject.Actor.Username,
+				Target:   subject.UserID,
+				TenantID: subject.TenantID,
+				IP:       c.ClientIP(),
+				Data: map[string]interface{}{
+					"actor_id":   subject.Actor.UserID,
+					"reason":     req.Reason,
+					"expires_at": expiresAt,
+				},
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"token":      token,
+			"token_type": "Bearer",
+			"expires_in": int64(cfg.ImpersonationTTL.Seconds()),
+			"user_id":    subject.UserID,
+			"username":   subject.Username,
+			"role":       subject.Role,
+			"tenant_id":  subject.TenantID,
+			"act":        subject.Actor,
+		})
+	}
+}
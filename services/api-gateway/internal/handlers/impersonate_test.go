@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/audit"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/middleware"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonation(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWT_SECRET: "test-secret", AccessTokenTTL: time.Minute, ImpersonationTTL: 5 * time.Minute, DefaultTenant: "acme"}
+	deps := newTestAuthDeps(cfg)
+	deps.Audit = audit.NewLog(store.NewMemory())
+
+	admin, err := issueTokens(newTestContext(), cfg, deps, tokens.Subject{UserID: "admin-1", Username: "root", Role: "admin"})
+	require.NoError(t, err)
+	issueTokens(newTestContext(), cfg, deps, tokens.Subject{UserID: "user-1", Username: "alice", Role: "user"})
+	issueTokens(newTestContext(), cfg, deps, tokens.Subject{UserID: "admin-2", Username: "ops", Role: "admin"})
+
+	router := gin.New()
+	protected := router.Group("/", middleware.Auth(cfg.JWT_SECRET,
+		middleware.WithKeySet(deps.Keys),
+		middleware.WithDenylist(deps.Denylist),
+		middleware.WithImpersonation(deps.Audit, []string{"DELETE"}),
+	))
+	protected.POST("/admin/impersonate/:user_id", middleware.RequireRole("admin"), Impersonate(cfg, deps))
+	whoami := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id"), "actor_id": c.GetString("actor_id"), "actor_username": c.GetString("actor_username")})
+	}
+	protected.GET("/files/:id", whoami)
+	protected.DELETE("/files/:id", whoami)
+	protected.POST("/api-keys", middleware.DenyImpersonation(), whoami)
+
+	send := func(method, path, token string, body gin.H) (*httptest.ResponseRecorder, gin.H) {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp gin.H
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	// Un admin ne peut pas se faire passer pour un autre admin ni pour un inconnu
+	w, _ := send("POST", "/admin/impersonate/admin-2", admin["token"].(string), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, _ = send("POST", "/admin/impersonate/nobody", admin["token"].(string), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, resp := send("POST", "/admin/impersonate/user-1", admin["token"].(string), gin.H{"reason": "ticket #42"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user-1", resp["user_id"])
+	assert.Equal(t, float64(300), resp["expires_in"])
+	assert.Nil(t, resp["refresh_token"])
+	token := resp["token"].(string)
+
+	// Le token porte l'utilisateur et l'admin (claim "act")
+	parsed, err := jwt.Parse(token, deps.Keys.Keyfunc)
+	require.NoError(t, err)
+	claims := parsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, "user-1", claims["user_id"])
+	assert.Equal(t, map[string]interface{}{"sub": "admin-1", "username": "root"}, claims["act"])
+
+	// Les deux identités sont dans le contexte
+	w, resp = send("GET", "/files/1", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, gin.H{"user_id": "user-1", "actor_id": "admin-1", "actor_username": "root"}, resp)
+
+	// Actions refusées : méthode bloquée et route protégée
+	w, resp = send("DELETE", "/files/1", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "not allowed while impersonating", resp["reason"])
+	w, _ = send("POST", "/api-keys", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	// Pas d'impersonation en chaîne : le token n'a pas le rôle admin
+	w, _ = send("POST", "/admin/impersonate/admin-2", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Un token normal n'est pas concerné
+	w, _ = send("DELETE", "/files/1", admin["token"].(string), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Chaque requête faite au nom de l'utilisateur est journalisée
+	ctx := context.Background()
+	started, _ := deps.Audit.List(ctx, "impersonation.started", 10)
+	require.Len(t, started, 1)
+	assert.Equal(t, "root", started[0].Actor)
+	assert.Equal(t, "user-1", started[0].Target)
+	assert.Equal(t, "ticket #42", started[0].Data["reason"])
+	requests, _ := deps.Audit.List(ctx, "impersonation.request", 10)
+	assert.Len(t, requests, 3)
+	blocked, _ := deps.Audit.List(ctx, "impersonation.blocked", 10)
+	require.Len(t, blocked, 1)
+	assert.Equal(t, "DELETE", blocked[0].Data["method"])
+	assert.Equal(t, float64(http.StatusForbidden), blocked[0].Data["status"])
+}
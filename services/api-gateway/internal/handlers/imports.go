@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/importer"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/jobs"
+)
+
+type ImportRequest struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// ImportFile lance l'import d'un fichier depuis une URL en tâche de fond.
+func ImportFile(imp *importer.Importer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ImportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		job, err := imp.Start(c.Request.Context(), c.GetString("tenant_id"), c.GetString("user_id"), req.URL)
+		if errors.Is(err, importer.ErrInvalidURL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start import"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"job":        job,
+			"status_url": "/api/v1/jobs/" + job.ID,
+		})
+	}
+}
+
+// GetJob retourne l'état d'un job de l'utilisateur.
+func GetJob(jobRepo *jobs.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := jobRepo.Get(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+		if errors.Is(err, jobs.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load job"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"job": job})
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/apikeys"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/middleware"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+)
+
+// IntrospectRequest suit la RFC 7662 (formulaire ou JSON).
+type IntrospectRequest struct {
+	Token         string `form:"token" json:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+}
+
+// Introspect vérifie un token pour le compte d'un service interne (RFC 7662).
+// Le service s'authentifie en Basic avec un client déclaré dans INTROSPECTION_CLIENTS.
+// Un token invalide, expiré ou révoqué donne {"active": false}, sans détail.
+func Introspect(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, ok := authenticateClient(c, cfg.IntrospectionClients)
+		if !ok {
+			c.Header("WWW-Authenticate", `Basic realm="introspection"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+			return
+		}
+
+		var req IntrospectRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+			return
+		}
+
+		var resp gin.H
+		var err error
+		if deps.APIKeys != nil && (req.TokenTypeHint == "api_key" || strings.HasPrefix(req.Token, "mck_")) {
+			resp, err = introspectAPIKey(c, deps, req.Token)
+		} else {
+			resp, err = introspectJWT(c, cfg, deps, req.Token)
+		}
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "temporarily_unavailable"})
+			return
+		}
+		if resp == nil {
+			resp = gin.H{"active": false}
+		} else {
+			resp["active"] = true
+			resp["client_id"] = clientID
+		}
+
+		// Les réponses ne doivent pas être mises en cache par un intermédiaire
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// authenticateClient vérifie les identifiants Basic du service appelant.
+func authenticateClient(c *gin.Context, clients map[string]string) (string, bool) {
+	clientID, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	expected, known := clients[clientID]
+	if !known || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+		return "", false
+	}
+	return clientID, true
+}
+
+// introspectJWT retourne nil pour un token inactif ; une erreur signale une panne
+// (révocation invérifiable).
+func introspectJWT(c *gin.Context, cfg *config.Config, deps *AuthDeps, tokenString string) (gin.H, error) {
+	claims, err := tokens.ParseClaims(tokenString, deps.Keys, deps.Claims)
+	if err != nil {
+		return nil, nil
+	}
+
+	if deps.Denylist != nil {
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
+		}
+		revoked, err := deps.Denylist.IsRevoked(c.Request.Context(), claims.ID, claims.UserID, issuedAt)
+		if err == nil && !revoked {
+			revoked, err = deps.Denylist.IsSessionRevoked(c.Request.Context(), claims.SessionID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, nil
+		}
+	}
+
+	resp := gin.H{
+		"token_type": "access_token",
+		"sub":        claims.UserID,
+		"username":   claims.Username,
+		"role":       claims.Role,
+		"tenant_id":  claims.TenantID,
+		"jti":        claims.ID,
+		// Les JWT ne portent pas de scope : ce sont les permissions du rôle
+		"scope": strings.Join(cfg.RolePermissions[claims.Role], " "),
+	}
+	if claims.ExpiresAt != nil {
+		resp["exp"] = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp["iat"] = claims.IssuedAt.Unix()
+	}
+	if claims.Issuer != "" {
+		resp["iss"] = claims.Issuer
+	}
+	if len(claims.Audience) > 0 {
+		resp["aud"] = claims.Audience
+	}
+	// Token d'impersonation : l'admin qui agit au nom de l'utilisateur
+	if claims.Actor != nil {
+		resp["act"] = claims.Actor
+	}
+	return resp, nil
+}
+
+func introspectAPIKey(c *gin.Context, deps *AuthDeps, plaintext string) (gin.H, error) {
+	key, err := deps.APIKeys.Authenticate(c.Request.Context(), plaintext)
+	if errors.Is(err, apikeys.ErrNotFound) || errors.Is(err, apikeys.ErrExpired) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Comme à l'utilisation de la clé : rôle actuel du membre, clé d'un membre retiré inactive
+	if deps.Tenants != nil {
+		err := middleware.ApplyMemberRole(c.Request.Context(), deps.Tenants, key)
+		if errors.Is(err, tenants.ErrRemoved) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp := gin.H{
+		"token_type": "api_key",
+		"sub":        key.UserID,
+		"username":   key.Username,
+		"role":       key.Role,
+		"tenant_id":  key.TenantID,
+		"scope":      strings.Join(key.Scopes, " "),
+		"iat":        key.CreatedAt.Unix(),
+	}
+	if key.ExpiresAt != nil {
+		resp["exp"] = key.ExpiresAt.Unix()
+	}
+	return resp, nil
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/apikeys"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/stretchr/testify/assert"
+)
+
+func introspect(router *gin.Engine, clientID, secret, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	form := url.Values{"token": {token}}
+	req, _ := http.NewRequest("POST", "/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, secret)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestIntrospect(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		JWT_SECRET:           "test-secret",
+		IntrospectionClients: map[string]string{"files": "files-secret"},
+		RolePermissions:      map[string][]string{"user": {"files:read", "files:write"}},
+	}
+	deps := newTestAuthDeps(cfg)
+	deps.APIKeys = apikeys.NewRepository(store.NewMemory())
+	router := gin.New()
+	router.POST("/introspect", Introspect(cfg, deps))
+
+	token, _ := generateJWT(deps, tokens.Subject{UserID: "123", Username: "alice", Role: "user", TenantID: "tenant-1"}, time.Minute)
+
+	// Client inconnu ou mauvais secret
+	w, _ := introspect(router, "", "", token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, _ = introspect(router, "files", "wrong", token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Token valide
+	w, resp := introspect(router, "files", "files-secret", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, resp["active"])
+	assert.Equal(t, "123", resp["sub"])
+	assert.Equal(t, "user", resp["role"])
+	assert.Equal(t, "files:read files:write", resp["scope"])
+	assert.NotNil(t, resp["exp"])
+
+	// Token invalide : aucune information
+	_, resp = introspect(router, "files", "files-secret", "not-a-token")
+	assert.Equal(t, map[string]interface{}{"active": false}, resp)
+
+	// Token révoqué
+	deps.Denylist.RevokeUser(context.Background(), "123")
+	_, resp = introspect(router, "files", "files-secret", token)
+	assert.Equal(t, false, resp["active"])
+
+	// Clé d'API
+	deps.Tenants.Join(context.Background(), "", "456", "bob", "user")
+	plaintext, _ := deps.APIKeys.Create(context.Background(), &apikeys.Key{UserID: "456", Role: "user", Scopes: []string{"files:read"}})
+	_, resp = introspect(router, "files", "files-secret", plaintext)
+	assert.Equal(t, true, resp["active"])
+	assert.Equal(t, "456", resp["sub"])
+	assert.Equal(t, "files:read", resp["scope"])
+}
+
+func TestIntrospectAPIKeyUsesMemberRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWT_SECRET: "test-secret", IntrospectionClients: map[string]string{"files": "files-secret"}}
+	deps := newTestAuthDeps(cfg)
+	deps.APIKeys = apikeys.NewRepository(store.NewMemory())
+	router := gin.New()
+	router.POST("/introspect", Introspect(cfg, deps))
+	ctx := context.Background()
+
+	deps.Tenants.Join(ctx, "acme", "123", "root", "admin")
+	plaintext, _ := deps.APIKeys.Create(ctx, &apikeys.Key{UserID: "123", Role: "admin", TenantID: "acme", Scopes: []string{"files:read", "admin"}})
+	_, resp := introspect(router, "files", "files-secret", plaintext)
+	assert.Equal(t, "admin", resp["role"])
+	assert.Equal(t, "files:read admin", resp["scope"])
+
+	// Rétrogradé après la création de la clé : ni rôle ni scope admin
+	deps.Tenants.AssignRole(ctx, "acme", "123", "user")
+	_, resp = introspect(router, "files", "files-secret", plaintext)
+	assert.Equal(t, true, resp["active"])
+	assert.Equal(t, "user", resp["role"])
+	assert.Equal(t, "files:read", resp["scope"])
+
+	// Membre retiré : la clé est inactive
+	deps.Tenants.Remove(ctx, "acme", "123")
+	_, resp = introspect(router, "files", "files-secret", plaintext)
+	assert.Equal(t, map[string]interface{}{"active": false}, resp)
+}
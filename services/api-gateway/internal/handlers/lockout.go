@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/lockout"
+)
+
+type UnlockLoginRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+}
+
+// checkLoginAllowed refuse la tentative si le compte ou l'IP est verrouillé.
+// Retourne false quand la réponse a été envoyée.
+func checkLoginAllowed(c *gin.Context, deps *AuthDeps, username string) bool {
+	if deps.Lockout == nil {
+		return true
+	}
+
+	retryAfter, err := deps.Lockout.Check(c.Request.Context(), username, c.ClientIP())
+	if errors.Is(err, lockout.ErrLocked) {
+		tooManyAttempts(c, retryAfter)
+		return false
+	}
+	if err != nil {
+		// Comme le rate limiting, continuer si le store n'est pas disponible
+		log.Printf("login lockout check failed: %v", err)
+	}
+	return true
+}
+
+// recordLoginFailure compte l'échec et répond 401 avec un message générique.
+func recordLoginFailure(c *gin.Context, deps *AuthDeps, username, message string) {
+	if deps.Lockout != nil {
+		retryAfter, err := deps.Lockout.Fail(c.Request.Context(), username, c.ClientIP())
+		if err != nil {
+			log.Printf("login lockout update failed: %v", err)
+		}
+		if retryAfter > 0 {
+			c.Header("Retry-After", retryAfterSeconds(retryAfter))
+		}
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+// recordLoginSuccess remet à zéro les compteurs une fois tous les facteurs vérifiés.
+func recordLoginSuccess(c *gin.Context, deps *AuthDeps, username string) {
+	if deps.Lockout == nil {
+		return
+	}
+	if err := deps.Lockout.Succeed(c.Request.Context(), username, c.ClientIP()); err != nil {
+		log.Printf("login lockout reset failed: %v", err)
+	}
+}
+
+// releaseLoginAttempt rend la tentative réservée par checkLoginAllowed quand elle
+// n'aboutit ni à un succès ni à un échec.
+func releaseLoginAttempt(c *gin.Context, deps *AuthDeps, username string) {
+	if deps.Lockout == nil {
+		return
+	}
+	if err := deps.Lockout.Release(c.Request.Context(), username, c.ClientIP()); err != nil {
+		log.Printf("login lockout release failed: %v", err)
+	}
+}
+
+func tooManyAttempts(c *gin.Context, retryAfter time.Duration) {
+	c.Header("Retry-After", retryAfterSeconds(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed login attempts",
+		"retry_after": retryAfterSeconds(retryAfter),
+	})
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// UnlockLogin lève le verrouillage d'un nom d'utilisateur et/ou d'une IP (admin).
+func UnlockLogin(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UnlockLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Username == "" && req.IP == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "username or ip is required"})
+			return
+		}
+
+		if err := deps.Lockout.Unlock(c.Request.Context(), c.GetString("username"), req.Username, req.IP); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock login"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Login unlocked successfully"})
+	}
+}
+
+// ListAuditLog retourne le journal d'audit, filtré par ?action=.
+func ListAuditLog(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		entries, err := deps.Audit.List(c.Request.Context(), c.Query("action"), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit log"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"entries": entries})
+	}
+}
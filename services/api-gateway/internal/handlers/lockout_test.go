@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/audit"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/lockout"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// rejectingTransport simule un service d'authentification qui refuse toujours.
+type rejectingTransport struct{}
+
+func (rejectingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		Status:     "401 Unauthorized",
+		StatusCode: http.StatusUnauthorized,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"error": "invalid credentials"}`)),
+	}, nil
+}
+
+func newLockoutRouter(options lockout.Options) (*gin.Engine, *AuthDeps) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{AuthServiceURL: "http://auth", JWT_SECRET: "test-secret"}
+	deps := newTestAuthDeps(cfg)
+	deps.Audit = audit.NewLog(store.NewMemory())
+	deps.Lockout = lockout.NewGuard(store.NewMemory(), options, deps.Audit)
+
+	router := gin.New()
+	router.POST("/login", Login(cfg, deps, &http.Client{Transport: rejectingTransport{}}))
+	router.POST("/unlock", func(c *gin.Context) {
+		c.Set("username", "admin")
+		c.Next()
+	}, UnlockLogin(deps))
+	return router, deps
+}
+
+func attemptLogin(router *gin.Engine, username string) *httptest.ResponseRecorder {
+	body := `{"username": "` + username + `", "password": "wrong"}`
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginLockoutAfterFailures(t *testing.T) {
+	router, deps := newLockoutRouter(lockout.Options{
+		MaxFailures:     3,
+		MaxIPFailures:   100,
+		Window:          time.Minute,
+		LockoutDuration: time.Minute,
+	})
+
+	for i := 0; i < 3; i++ {
+		w := attemptLogin(router, "alice")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid username or password")
+	}
+
+	// Le compte est verrouillé, quel que soit le mot de passe
+	w := attemptLogin(router, "Alice")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Les autres comptes ne sont pas affectés
+	assert.Equal(t, http.StatusUnauthorized, attemptLogin(router, "bob").Code)
+
+	entries, _ := deps.Audit.List(context.Background(), "login.locked", 0)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "user:alice", entries[0].Target)
+
+	// Un admin lève le verrouillage
+	req, _ := http.NewRequest("POST", "/unlock", bytes.NewBufferString(`{"username": "alice"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, http.StatusUnauthorized, attemptLogin(router, "alice").Code)
+	entries, _ = deps.Audit.List(context.Background(), "login.unlocked", 0)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "admin", entries[0].Actor)
+}
+
+func TestLoginProgressiveDelay(t *testing.T) {
+	router, _ := newLockoutRouter(lockout.Options{
+		MaxFailures:   10,
+		MaxIPFailures: 100,
+		Window:        time.Minute,
+		BaseDelay:     time.Minute,
+		MaxDelay:      time.Hour,
+	})
+
+	w := attemptLogin(router, "alice")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// Nouvelle tentative avant la fin du délai
+	w = attemptLogin(router, "alice")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestLoginIPLockout(t *testing.T) {
+	router, _ := newLockoutRouter(lockout.Options{
+		MaxFailures:     100,
+		MaxIPFailures:   2,
+		Window:          time.Minute,
+		LockoutDuration: time.Minute,
+	})
+
+	attemptLogin(router, "alice")
+	attemptLogin(router, "bob")
+
+	// Une même IP qui essaie plusieurs comptes est bloquée
+	assert.Equal(t, http.StatusTooManyRequests, attemptLogin(router, "carol").Code)
+}
+
+func TestLoginLockoutConcurrentAttempts(t *testing.T) {
+	router, _ := newLockoutRouter(lockout.Options{
+		MaxFailures:     3,
+		MaxIPFailures:   100,
+		Window:          time.Minute,
+		LockoutDuration: time.Minute,
+	})
+
+	// Des tentatives simultanées ne vérifient pas plus de mots de passe que le seuil
+	var wg sync.WaitGroup
+	codes := make(chan int, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- attemptLogin(router, "alice").Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	rejected := 0
+	for code := range codes {
+		if code == http.StatusUnauthorized {
+			rejected++
+		}
+	}
+	assert.LessOrEqual(t, rejected, 3)
+	assert.Equal(t, http.StatusTooManyRequests, attemptLogin(router, "alice").Code)
+}
+
+func TestLoginAuthServiceErrorReleasesAttempt(t *testing.T) {
+	router, deps := newLockoutRouter(lockout.Options{
+		MaxFailures:     2,
+		MaxIPFailures:   2,
+		Window:          time.Minute,
+		LockoutDuration: time.Minute,
+	})
+	cfg := &config.Config{AuthServiceURL: "http://auth", JWT_SECRET: "test-secret"}
+	router.POST("/login-unavailable", Login(cfg, deps, &http.Client{Transport: &MockRoundTripper{Error: errors.New("connection refused")}}))
+
+	// Une panne du service d'authentification n'est pas un échec de connexion
+	for i := 0; i < 5; i++ {
+		w, _ := postJSON(router, "/login-unavailable", LoginRequest{Username: "alice", Password: "secret"})
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	}
+	assert.Equal(t, http.StatusUnauthorized, attemptLogin(router, "alice").Code)
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/mfa"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+)
+
+type MFAChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+type MFAVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+	Cookie         bool   `json:"cookie"` // session navigateur, comme pour Login
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFARequiredRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// startMFAChallenge répond par un challenge si l'utilisateur a activé le MFA ou si
+// son rôle l'impose. Retourne false si les tokens peuvent être émis directement.
+func startMFAChallenge(c *gin.Context, cfg *config.Config, deps *AuthDeps, subject tokens.Subject) bool {
+	if deps.MFA == nil {
+		return false
+	}
+
+	ctx := c.Request.Context()
+	enabled, err := deps.MFA.Enabled(ctx, subject.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check MFA"})
+		return true
+	}
+	enrollment := false
+	if !enabled {
+		required, err := deps.MFA.Required(ctx, subject.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check MFA"})
+			return true
+		}
+		if !required {
+			return false
+		}
+		// Le rôle impose le MFA : l'utilisateur enrôle un appareil avant d'obtenir ses tokens
+		enrollment = true
+	}
+
+	token, err := deps.MFA.StartChallenge(ctx, subject, enrollment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start MFA challenge"})
+		return true
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required":            true,
+		"mfa_enrollment_required": enrollment,
+		"challenge_token":         token,
+		"expires_in":              int64(cfg.MFAChallengeTTL.Seconds()),
+	})
+	return true
+}
+
+// MFAVerify termine une connexion en deux étapes : le code TOTP (ou un code de
+// secours) échange le challenge contre les tokens.
+func MFAVerify(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFAVerifyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := c.Request.Context()
+		pending, err := deps.MFA.Challenge(ctx, req.ChallengeToken)
+		if errors.Is(err, mfa.ErrInvalidChallenge) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA challenge"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify MFA code"})
+			return
+		}
+		// Les codes MFA comptent dans le verrouillage de la connexion, comme les mots de passe
+		username := pending.Subject.Username
+		if !checkLoginAllowed(c, deps, username) {
+			return
+		}
+
+		challenge, recoveryCodes, err := deps.MFA.CompleteChallenge(ctx, req.ChallengeToken, req.Code)
+		switch {
+		case errors.Is(err, mfa.ErrInvalidCode):
+			recordLoginFailure(c, deps, username, "Invalid MFA code")
+			return
+		case errors.Is(err, mfa.ErrInvalidChallenge):
+			releaseLoginAttempt(c, deps, username)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA challenge"})
+			return
+		case errors.Is(err, mfa.ErrNotEnrolled):
+			releaseLoginAttempt(c, deps, username)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "MFA enrollment not started", "enroll_url": "/api/v1/auth/mfa/enroll"})
+			return
+		case err != nil:
+			releaseLoginAttempt(c, deps, username)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify MFA code"})
+			return
+		}
+		recordLoginSuccess(c, deps, username)
+
+		resp, err := issueTokens(c, cfg, deps, challenge.Subject)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		if recoveryCodes != nil {
+			resp["recovery_codes"] = recoveryCodes
+		}
+
+		respondWithTokens(c, cfg, resp, req.Cookie)
+	}
+}
+
+// MFAEnrollChallenge démarre l'enrôlement pendant une connexion dont le rôle impose le MFA.
+func MFAEnrollChallenge(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFAChallengeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		challenge, err := deps.MFA.Challenge(c.Request.Context(), req.ChallengeToken)
+		if err != nil || !challenge.Enrollment {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA challenge"})
+			return
+		}
+		beginEnrollment(c, deps, challenge.Subject.UserID, challenge.Subject.Username)
+	}
+}
+
+// GetMFAStatus retourne l'état MFA de l'utilisateur.
+func GetMFAStatus(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		enabled, err := deps.MFA.Enabled(ctx, c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check MFA"})
+			return
+		}
+		required, err := deps.MFA.Required(ctx, c.GetString("role"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check MFA"})
+			return
+		}
+
+		resp := gin.H{"enabled": enabled, "required": required}
+		if enabled {
+			remaining, _ := deps.MFA.Remaining(ctx, c.GetString("user_id"))
+			resp["recovery_codes_remaining"] = remaining
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// EnrollMFA démarre l'enrôlement d'un utilisateur connecté.
+func EnrollMFA(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		beginEnrollment(c, deps, c.GetString("user_id"), c.GetString("username"))
+	}
+}
+
+// ConfirmMFA active le MFA avec un premier code et retourne les codes de secours.
+func ConfirmMFA(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		codes, err := deps.MFA.Confirm(c.Request.Context(), c.GetString("user_id"), req.Code)
+		if !handleMFAError(c, err) {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "recovery_codes": codes})
+	}
+}
+
+// RegenerateRecoveryCodes remplace les codes de secours de l'utilisateur.
+func RegenerateRecoveryCodes(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		codes, err := deps.MFA.RegenerateRecoveryCodes(c.Request.Context(), c.GetString("user_id"), req.Code)
+		if !handleMFAError(c, err) {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	}
+}
+
+// DisableMFA désactive le MFA, sauf si le rôle de l'utilisateur l'impose.
+func DisableMFA(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err := deps.MFA.Disable(c.Request.Context(), c.GetString("user_id"), c.GetString("role"), req.Code)
+		if !handleMFAError(c, err) {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+	}
+}
+
+// GetMFARequiredRoles retourne les rôles pour lesquels le MFA est obligatoire (admin).
+func GetMFARequiredRoles(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles, err := deps.MFA.RequiredRoles(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load MFA settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"roles": roles})
+	}
+}
+
+// SetMFARequiredRoles modifie les rôles pour lesquels le MFA est obligatoire (admin).
+// S'applique aux connexions suivantes.
+func SetMFARequiredRoles(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFARequiredRolesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := deps.MFA.SetRequiredRoles(c.Request.Context(), req.Roles); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save MFA settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"roles": req.Roles})
+	}
+}
+
+func beginEnrollment(c *gin.Context, deps *AuthDeps, userID, account string) {
+	secret, uri, err := deps.MFA.Begin(c.Request.Context(), userID, account)
+	if !handleMFAError(c, err) {
+		return
+	}
+
+	// L'URI est à afficher sous forme de QR code ; le secret permet une saisie manuelle
+	c.JSON(http.StatusOK, gin.H{"secret": secret, "provisioning_uri": uri})
+}
+
+func handleMFAError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, mfa.ErrInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid MFA code"})
+		return false
+	case errors.Is(err, mfa.ErrNotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "MFA is not enrolled"})
+		return false
+	case errors.Is(err, mfa.ErrAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "MFA is already enabled"})
+		return false
+	case errors.Is(err, mfa.ErrRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": "MFA is required for your role"})
+		return false
+	case errors.Is(err, mfa.ErrTooManyAttempts):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many MFA attempts"})
+		return false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "MFA operation failed"})
+		return false
+	}
+	return true
+}
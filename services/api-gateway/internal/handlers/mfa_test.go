@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/lockout"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/mfa"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupMFARouter(t *testing.T, role string) (*gin.Engine, *AuthDeps) {
+	gin.SetMode(gin.TestMode)
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(AuthResponse{UserID: "123", Username: "alice", Role: role})
+	}))
+	t.Cleanup(authService.Close)
+
+	cfg := &config.Config{AuthServiceURL: authService.URL, JWT_SECRET: "test-secret", AccessTokenTTL: time.Minute, MFAChallengeTTL: time.Minute}
+	deps := newTestAuthDeps(cfg)
+	deps.MFA = mfa.NewService(store.NewMemory(), "MiniCloud", time.Minute, []string{"admin"})
+
+	router := gin.New()
+	router.POST("/login", Login(cfg, deps))
+	router.POST("/mfa/verify", MFAVerify(cfg, deps))
+	router.POST("/mfa/enroll", MFAEnrollChallenge(deps))
+	return router, deps
+}
+
+func postJSON(router *gin.Engine, path string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestLoginWithMFA(t *testing.T) {
+	router, deps := setupMFARouter(t, "user")
+	ctx := context.Background()
+
+	// Activer le MFA de l'utilisateur
+	secret, _, err := deps.MFA.Begin(ctx, "123", "alice")
+	assert.NoError(t, err)
+	code, _ := mfa.Code(secret, time.Now())
+	recoveryCodes, err := deps.MFA.Confirm(ctx, "123", code)
+	assert.NoError(t, err)
+	assert.Len(t, recoveryCodes, 10)
+
+	// Le mot de passe seul ne donne qu'un challenge
+	w, resp := postJSON(router, "/login", LoginRequest{Username: "alice", Password: "secret"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, resp["mfa_required"])
+	assert.Nil(t, resp["token"])
+	challenge := resp["challenge_token"].(string)
+
+	// Mauvais code, puis code déjà utilisé à l'enrôlement
+	w, _ = postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: challenge, Code: "000000"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, _ = postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: challenge, Code: code})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Code de la période suivante
+	next, _ := mfa.Code(secret, time.Now().Add(30*time.Second))
+	w, resp = postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: challenge, Code: next})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, resp["token"])
+
+	// Le challenge est consommé
+	w, _ = postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: challenge, Code: next})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Un code de secours n'est utilisable qu'une fois
+	_, resp = postJSON(router, "/login", LoginRequest{Username: "alice", Password: "secret"})
+	w, _ = postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: resp["challenge_token"].(string), Code: recoveryCodes[0]})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	_, resp = postJSON(router, "/login", LoginRequest{Username: "alice", Password: "secret"})
+	w, _ = postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: resp["challenge_token"].(string), Code: recoveryCodes[0]})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLoginRequiresMFAEnrollmentForRole(t *testing.T) {
+	router, _ := setupMFARouter(t, "admin")
+
+	w, resp := postJSON(router, "/login", LoginRequest{Username: "alice", Password: "secret"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, resp["mfa_enrollment_required"])
+	challenge := resp["challenge_token"].(string)
+
+	// Enrôlement pendant la connexion
+	w, resp = postJSON(router, "/mfa/enroll", MFAChallengeRequest{ChallengeToken: challenge})
+	assert.Equal(t, http.StatusOK, w.Code)
+	uri, err := url.Parse(resp["provisioning_uri"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "MiniCloud", uri.Query().Get("issuer"))
+
+	code, _ := mfa.Code(resp["secret"].(string), time.Now())
+	w, resp = postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: challenge, Code: code})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, resp["token"])
+	assert.Len(t, resp["recovery_codes"], 10)
+}
+
+func TestMFACodesCountTowardLoginLockout(t *testing.T) {
+	router, deps := setupMFARouter(t, "user")
+	deps.Lockout = lockout.NewGuard(store.NewMemory(), lockout.Options{
+		MaxFailures:     3,
+		MaxIPFailures:   100,
+		Window:          time.Minute,
+		LockoutDuration: time.Minute,
+	}, nil)
+	ctx := context.Background()
+	secret, _, err := deps.MFA.Begin(ctx, "123", "alice")
+	assert.NoError(t, err)
+	code, _ := mfa.Code(secret, time.Now())
+	_, err = deps.MFA.Confirm(ctx, "123", code)
+	assert.NoError(t, err)
+
+	// Le bon mot de passe ne remet pas les compteurs à zéro : seuls les codes
+	// MFA refusés les font avancer jusqu'au verrouillage
+	for i := 0; i < 3; i++ {
+		_, resp := postJSON(router, "/login", LoginRequest{Username: "alice", Password: "secret"})
+		w, _ := postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: resp["challenge_token"].(string), Code: "000000"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid MFA code")
+	}
+
+	w, _ := postJSON(router, "/login", LoginRequest{Username: "alice", Password: "secret"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestMFAVerifyResetsLockout(t *testing.T) {
+	router, deps := setupMFARouter(t, "user")
+	deps.Lockout = lockout.NewGuard(store.NewMemory(), lockout.Options{
+		MaxFailures:     2,
+		MaxIPFailures:   100,
+		Window:          time.Minute,
+		LockoutDuration: time.Minute,
+	}, nil)
+	ctx := context.Background()
+	secret, _, err := deps.MFA.Begin(ctx, "123", "alice")
+	assert.NoError(t, err)
+	code, _ := mfa.Code(secret, time.Now())
+	_, err = deps.MFA.Confirm(ctx, "123", code)
+	assert.NoError(t, err)
+
+	_, resp := postJSON(router, "/login", LoginRequest{Username: "alice", Password: "secret"})
+	challenge := resp["challenge_token"].(string)
+	w, _ := postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: challenge, Code: "000000"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	next, _ := mfa.Code(secret, time.Now().Add(30*time.Second))
+	w, _ = postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: challenge, Code: next})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Le second facteur vérifié efface l'échec précédent
+	_, resp = postJSON(router, "/login", LoginRequest{Username: "alice", Password: "secret"})
+	w, _ = postJSON(router, "/mfa/verify", MFAVerifyRequest{ChallengeToken: resp["challenge_token"].(string), Code: "000000"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, _ = postJSON(router, "/login", LoginRequest{Username: "alice", Password: "secret"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
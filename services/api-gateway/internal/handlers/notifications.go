@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+)
+
+// ListNotifications retourne les dernières notifications de l'utilisateur.
+func ListNotifications(inbox *notify.Inbox) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		events, err := inbox.List(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list notifications"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"notifications": events})
+	}
+}
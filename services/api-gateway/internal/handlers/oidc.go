@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/oidc"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+)
+
+// Cookie qui lie le state OIDC au navigateur qui a démarré la connexion
+const (
+	oidcStateCookie     = "mc_oidc_state"
+	oidcStateCookiePath = refreshCookiePath + "/oidc"
+)
+
+// OIDCLogin redirige le navigateur vers le fournisseur d'identité (code + PKCE).
+// Le state est aussi posé en cookie : le callback n'est accepté que dans ce navigateur.
+func OIDCLogin(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authURL, state, err := deps.OIDC.Begin(c.Request.Context(), c.Param("provider"))
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+			return
+		}
+		if err != nil {
+			log.Printf("OIDC login with %s failed: %v", c.Param("provider"), err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider error"})
+			return
+		}
+
+		setOIDCStateCookie(c, cfg, state, int(oidc.StateTTL.Seconds()))
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// setOIDCStateCookie pose le cookie de state. SameSite=Lax quelle que soit la
+// configuration : le retour du fournisseur est une navigation inter-sites.
+func setOIDCStateCookie(c *gin.Context, cfg *config.Config, state string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     oidcStateCookiePath,
+		Domain:   cfg.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   cfg.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OIDCCallback termine la connexion : vérifie le state, échange le code, valide
+// l'ID token puis délivre les tokens du gateway.
+func OIDCCallback(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		providerName := c.Param("provider")
+
+		provider, err := deps.OIDC.Provider(providerName)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+			return
+		}
+
+		// Le fournisseur peut renvoyer une erreur (refus de l'utilisateur, etc.)
+		if providerErr := c.Query("error"); providerErr != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":             "Identity provider denied the login",
+				"provider_error":    providerErr,
+				"error_description": c.Query("error_description"),
+			})
+			return
+		}
+
+		// Le state doit venir du navigateur qui a démarré la connexion (login CSRF)
+		state := c.Query("state")
+		cookie, _ := c.Cookie(oidcStateCookie)
+		setOIDCStateCookie(c, cfg, "", -1)
+		if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookie)) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state"})
+			return
+		}
+
+		pending, err := deps.OIDC.Consume(ctx, providerName, state)
+		if errors.Is(err, oidc.ErrInvalidState) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load login state"})
+			return
+		}
+
+		code := c.Query("code")
+		if code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing authorization code"})
+			return
+		}
+
+		rawIDToken, err := provider.Exchange(ctx, code, pending.CodeVerifier)
+		if err != nil {
+			log.Printf("OIDC code exchange with %s failed: %v", providerName, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider error"})
+			return
+		}
+
+		claims, err := provider.VerifyIDToken(ctx, rawIDToken, pending.Nonce)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+			return
+		}
+
+		userID, username, role := provider.Identity(claims)
+		subject := tokens.Subject{
+			UserID:   userID,
+			Username: username,
+			Role:     role,
+			TenantID: provider.Tenant(),
+		}
+		err = syncMembership(ctx, cfg, deps, &subject)
+		if errors.Is(err, tenants.ErrRemoved) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "User has been removed from the tenant"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tenant membership"})
+			return
+		}
+
+		// Même second facteur qu'une connexion par mot de passe
+		if startMFAChallenge(c, cfg, deps, subject) {
+			return
+		}
+
+		resp, err := issueTokens(c, cfg, deps, subject)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/mfa"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/oidc"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockOIDCProvider simule un fournisseur OIDC : découverte, JWKS et endpoint token.
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// code -> requête d'autorisation (nonce, code_challenge)
+	codes map[string]url.Values
+	// nonce forcé dans l'ID token, pour simuler un rejeu
+	nonceOverride string
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	m := &mockOIDCProvider{key: key, codes: make(map[string]url.Values)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "mock-1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		authReq, ok := m.codes[r.PostForm.Get("code")]
+		// Vérification PKCE comme le ferait un vrai fournisseur
+		if !ok || oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != authReq.Get("code_challenge") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		nonce := authReq.Get("nonce")
+		if m.nonceOverride != "" {
+			nonce = m.nonceOverride
+		}
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":                m.server.URL,
+			"aud":                "gateway",
+			"sub":                "42",
+			"preferred_username": "alice",
+			"groups":             []string{"staff", "platform-admins"},
+			"nonce":              nonce,
+			"exp":                time.Now().Add(time.Minute).Unix(),
+			"iat":                time.Now().Unix(),
+		})
+		idToken.Header["kid"] = "mock-1"
+		signed, _ := idToken.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	m.server = httptest.NewServer(mux)
+	return m
+}
+
+// authorize simule le passage de l'utilisateur chez le fournisseur et retourne code et state.
+func (m *mockOIDCProvider) authorize(t *testing.T, location string) (string, string) {
+	u, err := url.Parse(location)
+	assert.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.NotEmpty(t, q.Get("nonce"))
+	code := "code-" + q.Get("state")
+	m.codes[code] = q
+	return code, q.Get("state")
+}
+
+func setupOIDCRouter(provider *mockOIDCProvider) *gin.Engine {
+	router, _ := setupOIDCRouterWithDeps(provider)
+	return router
+}
+
+func setupOIDCRouterWithDeps(provider *mockOIDCProvider) (*gin.Engine, *AuthDeps) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cfg := &config.Config{JWT_SECRET: "test-secret", AccessTokenTTL: time.Minute}
+	deps := newTestAuthDeps(cfg)
+	deps.OIDC = oidc.NewRegistry([]config.OIDCProvider{{
+		Name:         "corp",
+		Issuer:       provider.server.URL,
+		ClientID:     "gateway",
+		RedirectURL:  "http://gateway/api/v1/auth/oidc/corp/callback",
+		RoleClaim:    "groups",
+		RoleMappings: []config.RoleMapping{{Value: "platform-admins", Role: "admin"}},
+	}}, store.NewMemory(), provider.server.Client())
+
+	router.GET("/oidc/:provider/login", OIDCLogin(cfg, deps))
+	router.GET("/oidc/:provider/callback", OIDCCallback(cfg, deps))
+	return router, deps
+}
+
+// oidcLogin démarre la connexion et retourne l'URL du fournisseur et le cookie de state.
+func oidcLogin(t *testing.T, router *gin.Engine) (string, *http.Cookie) {
+	req, _ := http.NewRequest("GET", "/oidc/corp/login", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+	var stateCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oidcStateCookie {
+			stateCookie = cookie
+		}
+	}
+	if assert.NotNil(t, stateCookie) {
+		assert.True(t, stateCookie.HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, stateCookie.SameSite)
+	}
+	return w.Header().Get("Location"), stateCookie
+}
+
+func oidcCallback(router *gin.Engine, code, state string, stateCookie *http.Cookie) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/oidc/corp/callback?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(state), nil)
+	if stateCookie != nil {
+		req.AddCookie(stateCookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	defer provider.server.Close()
+	router := setupOIDCRouter(provider)
+
+	location, stateCookie := oidcLogin(t, router)
+	code, state := provider.authorize(t, location)
+	w := oidcCallback(router, code, state, stateCookie)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NotEmpty(t, resp["token"])
+	assert.Equal(t, "corp:42", resp["user_id"])
+	assert.Equal(t, "alice", resp["username"])
+	assert.Equal(t, "admin", resp["role"])
+
+	// Le state est à usage unique
+	w = oidcCallback(router, code, state, stateCookie)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOIDCLoginRequiresMFA(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	defer provider.server.Close()
+	router, deps := setupOIDCRouterWithDeps(provider)
+	// Le fournisseur donne le rôle admin, pour lequel le MFA est obligatoire
+	deps.MFA = mfa.NewService(store.NewMemory(), "MiniCloud", time.Minute, []string{"admin"})
+
+	location, stateCookie := oidcLogin(t, router)
+	code, state := provider.authorize(t, location)
+	w := oidcCallback(router, code, state, stateCookie)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, true, resp["mfa_required"])
+	assert.Equal(t, true, resp["mfa_enrollment_required"])
+	assert.NotEmpty(t, resp["challenge_token"])
+	assert.Nil(t, resp["token"])
+}
+
+func TestOIDCRejectsNonceMismatch(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	defer provider.server.Close()
+	provider.nonceOverride = "replayed-nonce"
+	router := setupOIDCRouter(provider)
+
+	location, stateCookie := oidcLogin(t, router)
+	code, state := provider.authorize(t, location)
+	w := oidcCallback(router, code, state, stateCookie)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid ID token")
+}
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	defer provider.server.Close()
+	router := setupOIDCRouter(provider)
+
+	// Un attaquant démarre la connexion et fait ouvrir son callback à la victime,
+	// dont le navigateur n'a pas le cookie de state
+	location, _ := oidcLogin(t, router)
+	code, state := provider.authorize(t, location)
+	w := oidcCallback(router, code, state, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Le cookie d'une autre connexion ne convient pas non plus
+	_, otherCookie := oidcLogin(t, router)
+	w = oidcCallback(router, code, state, otherCookie)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOIDCExchangeErrorIsGeneric(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	defer provider.server.Close()
+	router := setupOIDCRouter(provider)
+
+	location, stateCookie := oidcLogin(t, router)
+	_, state := provider.authorize(t, location)
+	w := oidcCallback(router, "unknown-code", state, stateCookie)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.JSONEq(t, `{"error":"Identity provider error"}`, w.Body.String())
+}
+
+func TestOIDCUnknownProvider(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	defer provider.server.Close()
+	router := setupOIDCRouter(provider)
+
+	req, _ := http.NewRequest("GET", "/oidc/unknown/login", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/middleware"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/policy"
+)
+
+// ExplainPolicy évalue une action sans l'exécuter et détaille les règles appliquées.
+// Par défaut le sujet est l'appelant ; seul un admin peut simuler un autre sujet.
+func ExplainPolicy(engine *policy.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req policy.Input
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		in := middleware.PolicyInput(c, req.Action)
+		if req.Subject.UserID != "" || req.Subject.Role != "" {
+			if c.GetString("role") != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden", "reason": "only admins can explain another subject"})
+				return
+			}
+			in.Subject = req.Subject
+		}
+		in.Resource = req.Resource
+		for k, v := range req.Request {
+			in.Request[k] = v
+		}
+
+		decision := engine.Evaluate(in)
+		c.JSON(http.StatusOK, gin.H{"input": in, "decision": decision})
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/users"
+)
+
+// AuthProvider vérifie les identifiants et gère les comptes : service
+// d'authentification distant ou annuaire embarqué (voir AUTH_PROVIDER).
+type AuthProvider interface {
+	Login(ctx context.Context, username, password string) (*AuthResponse, error)
+	Register(ctx context.Context, req RegisterRequest) (*AuthResponse, error)
+	ResetPassword(ctx context.Context, userID, password string) error
+}
+
+// authProvider retourne le fournisseur configuré, ou le service distant appelé avec
+// le client donné.
+func authProvider(cfg *config.Config, deps *AuthDeps, client []*http.Client) AuthProvider {
+	if deps.Provider != nil {
+		return deps.Provider
+	}
+	httpClient := http.DefaultClient
+	if len(client) > 0 {
+		httpClient = client[0]
+	}
+	return &remoteProvider{client: httpClient, url: cfg.AuthServiceURL}
+}
+
+// credentialsRejected indique que le fournisseur a refusé les identifiants, par
+// opposition à une panne.
+func credentialsRejected(err error) bool {
+	var serviceErr *authServiceError
+	return errors.Is(err, users.ErrInvalidCredentials) || (errors.As(err, &serviceErr) && serviceErr.rejected())
+}
+
+// remoteProvider délègue au service d'authentification (AUTH_SERVICE_URL).
+type remoteProvider struct {
+	client *http.Client
+	url    string
+}
+
+func (p *remoteProvider) Login(ctx context.Context, username, password string) (*AuthResponse, error) {
+	return callAuthService(ctx, p.client, p.url+"/login", LoginRequest{Username: username, Password: password})
+}
+
+func (p *remoteProvider) Register(ctx context.Context, req RegisterRequest) (*AuthResponse, error) {
+	return callAuthService(ctx, p.client, p.url+"/register", req)
+}
+
+func (p *remoteProvider) ResetPassword(ctx context.Context, userID, password string) error {
+	_, err := callAuthService(ctx, p.client, p.url+"/reset-password", gin.H{"user_id": userID, "password": password})
+	return err
+}
+
+// EmbeddedProvider authentifie les utilisateurs de l'annuaire embarqué.
+type EmbeddedProvider struct {
+	users       *users.Repository
+	defaultRole string
+}
+
+// NewEmbeddedProvider crée le fournisseur embarqué ; les nouveaux comptes reçoivent defaultRole.
+func NewEmbeddedProvider(repo *users.Repository, defaultRole string) *EmbeddedProvider {
+	return &EmbeddedProvider{users: repo, defaultRole: defaultRole}
+}
+
+func (p *EmbeddedProvider) Login(ctx context.Context, username, password string) (*AuthResponse, error) {
+	user, err := p.users.Authenticate(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	return userResponse(user), nil
+}
+
+func (p *EmbeddedProvider) Register(ctx context.Context, req RegisterRequest) (*AuthResponse, error) {
+	user := &users.User{Username: req.Username, Email: req.Email, Role: p.defaultRole}
+	if err := p.users.Create(ctx, user, req.Password); err != nil {
+		return nil, err
+	}
+	return userResponse(user), nil
+}
+
+func (p *EmbeddedProvider) ResetPassword(ctx context.Context, userID, password string) error {
+	return p.users.SetPassword(ctx, userID, password)
+}
+
+func userResponse(user *users.User) *AuthResponse {
+	return &AuthResponse{UserID: user.ID, Username: user.Username, Role: user.Role, TenantID: user.TenantID}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/sessions"
+)
+
+// ListSessions liste les sessions ouvertes de l'utilisateur ; la session de la
+// requête est marquée "current".
+func ListSessions(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := deps.Sessions.List(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+			return
+		}
+
+		current := c.GetString("session_id")
+		resp := make([]gin.H, 0, len(list))
+		for _, session := range list {
+			resp = append(resp, gin.H{
+				"id":           session.ID,
+				"device":       session.Device,
+				"user_agent":   session.UserAgent,
+				"ip":           session.IP,
+				"created_at":   session.CreatedAt,
+				"last_seen_at": session.LastSeenAt,
+				"current":      session.ID == current,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"sessions": resp})
+	}
+}
+
+// RevokeSession ferme une session de l'utilisateur : son refresh token est révoqué
+// et ses tokens d'accès sont refusés immédiatement.
+func RevokeSession(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		userID := c.GetString("user_id")
+		id := c.Param("id")
+		if _, err := deps.Sessions.Get(ctx, userID, id); err != nil {
+			if errors.Is(err, sessions.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session"})
+			return
+		}
+		if err := endSession(ctx, deps, userID, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+		if id == c.GetString("session_id") && c.GetString("auth_method") == "cookie" {
+			clearSession(c, cfg)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Session revoked", "id": id})
+	}
+}
+
+// RevokeSessions déconnecte l'utilisateur de tous ses appareils. Avec
+// ?keep_current=true, la session de la requête reste ouverte.
+func RevokeSessions(cfg *config.Config, deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		userID := c.GetString("user_id")
+		current := c.GetString("session_id")
+
+		if c.Query("keep_current") == "true" && current != "" {
+			list, err := deps.Sessions.List(ctx, userID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+				return
+			}
+			for _, session := range list {
+				if session.ID == current {
+					continue
+				}
+				if err := endSession(ctx, deps, userID, session.ID); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+					return
+				}
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked"})
+			return
+		}
+
+		if err := endAllSessions(ctx, deps, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+			return
+		}
+		if c.GetString("auth_method") == "cookie" {
+			clearSession(c, cfg)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked"})
+	}
+}
+
+// endSession révoque la famille de refresh tokens et les tokens d'accès d'une session.
+func endSession(ctx context.Context, deps *AuthDeps, userID, sessionID string) error {
+	if err := deps.RefreshTokens.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := deps.Denylist.RevokeSession(ctx, sessionID); err != nil {
+		return err
+	}
+	if deps.Sessions == nil {
+		return nil
+	}
+	return deps.Sessions.Delete(ctx, userID, sessionID)
+}
+
+// endAllSessions révoque tous les tokens de l'utilisateur, y compris ceux émis
+// avant le suivi des sessions.
+func endAllSessions(ctx context.Context, deps *AuthDeps, userID string) error {
+	if err := deps.Denylist.RevokeUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := deps.RefreshTokens.RevokeUser(ctx, userID); err != nil {
+		return err
+	}
+	if deps.Sessions == nil {
+		return nil
+	}
+	return deps.Sessions.DeleteUser(ctx, userID)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/middleware"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/sessions"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSessionsRouter(t *testing.T) (*gin.Engine, func(userAgent string) gin.H) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWT_SECRET: "test-secret", AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour}
+	deps := newTestAuthDeps(cfg)
+	deps.Sessions = sessions.NewRepository(store.NewMemory(), time.Hour)
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusOK, `{"user_id": "123", "username": "alice", "role": "user"}`), nil
+	})}
+
+	router := gin.New()
+	router.POST("/auth/login", Login(cfg, deps, client))
+	router.POST("/auth/refresh", Refresh(cfg, deps))
+	protected := router.Group("/", middleware.Auth(cfg.JWT_SECRET,
+		middleware.WithKeySet(deps.Keys),
+		middleware.WithDenylist(deps.Denylist),
+		middleware.WithSessions(deps.Sessions),
+	))
+	protected.POST("/auth/logout", Logout(cfg, deps))
+	protected.GET("/auth/sessions", ListSessions(deps))
+	protected.DELETE("/auth/sessions", RevokeSessions(cfg, deps))
+	protected.DELETE("/auth/sessions/:id", RevokeSession(cfg, deps))
+
+	login := func(userAgent string) gin.H {
+		body, _ := json.Marshal(gin.H{"username": "alice", "password": "secret"})
+		req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp gin.H
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+	return router, login
+}
+
+func sessionRequest(router *gin.Engine, method, path string, token interface{}) (*httptest.ResponseRecorder, gin.H) {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token.(string))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp gin.H
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestListAndRevokeSessions(t *testing.T) {
+	router, login := newSessionsRouter(t)
+	laptop := login("Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0")
+	phone := login("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Version/17.0 Mobile/15E148 Safari/604.1")
+	assert.NotEmpty(t, laptop["session_id"])
+	assert.NotEqual(t, laptop["session_id"], phone["session_id"])
+
+	// Les deux sessions sont listées, celle de la requête est marquée
+	w, resp := sessionRequest(router, "GET", "/auth/sessions", laptop["token"])
+	require.Equal(t, http.StatusOK, w.Code)
+	list := resp["sessions"].([]interface{})
+	require.Len(t, list, 2)
+	devices := map[string]bool{}
+	for _, item := range list {
+		session := item.(map[string]interface{})
+		devices[session["device"].(string)] = true
+		assert.Equal(t, session["id"] == laptop["session_id"], session["current"])
+	}
+	assert.True(t, devices["Firefox on Linux"])
+	assert.True(t, devices["Safari on iOS"])
+
+	// Fermer la session du téléphone depuis le portable
+	w, _ = sessionRequest(router, "DELETE", "/auth/sessions/"+phone["session_id"].(string), laptop["token"])
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Ses tokens sont refusés immédiatement
+	w, resp = sessionRequest(router, "GET", "/auth/sessions", phone["token"])
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Token revoked", resp["error"])
+	body, _ := json.Marshal(gin.H{"refresh_token": phone["refresh_token"]})
+	req, _ := http.NewRequest("POST", "/auth/refresh", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Le portable reste connecté
+	w, resp = sessionRequest(router, "GET", "/auth/sessions", laptop["token"])
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, resp["sessions"], 1)
+
+	// Une session inconnue ou d'un autre utilisateur n'est pas trouvée
+	w, _ = sessionRequest(router, "DELETE", "/auth/sessions/unknown", laptop["token"])
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	router, login := newSessionsRouter(t)
+	first := login("curl/8.5.0")
+	second := login("curl/8.5.0")
+	third := login("curl/8.5.0")
+
+	// Garder la session courante
+	w, _ := sessionRequest(router, "DELETE", "/auth/sessions?keep_current=true", first["token"])
+	require.Equal(t, http.StatusOK, w.Code)
+	w, _ = sessionRequest(router, "GET", "/auth/sessions", second["token"])
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, resp := sessionRequest(router, "GET", "/auth/sessions", first["token"])
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, resp["sessions"], 1)
+
+	// Déconnexion de tous les appareils
+	fourth := login("curl/8.5.0")
+	w, _ = sessionRequest(router, "DELETE", "/auth/sessions", fourth["token"])
+	require.Equal(t, http.StatusOK, w.Code)
+	for _, session := range []gin.H{first, third, fourth} {
+		w, _ = sessionRequest(router, "GET", "/auth/sessions", session["token"])
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestLogoutEndsSession(t *testing.T) {
+	router, login := newSessionsRouter(t)
+	laptop := login("curl/8.5.0")
+	phone := login("curl/8.5.0")
+
+	w, _ := sessionRequest(router, "POST", "/auth/logout", phone["token"])
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// La session fermée n'est plus listée et son refresh token est révoqué
+	w, resp := sessionRequest(router, "GET", "/auth/sessions", laptop["token"])
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, resp["sessions"], 1)
+	body, _ := json.Marshal(gin.H{"refresh_token": phone["refresh_token"]})
+	req, _ := http.NewRequest("POST", "/auth/refresh", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLogoutRejectsForeignRefreshToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWT_SECRET: "test-secret"}
+	deps := newTestAuthDeps(cfg)
+	router := gin.New()
+	router.POST("/auth/logout", func(c *gin.Context) {
+		c.Set("user_id", "123")
+		c.Set("jti", "jti-1")
+		c.Set("token_expires_at", time.Now().Add(time.Minute))
+	}, Logout(cfg, deps))
+
+	ctx := context.Background()
+	victim, _ := deps.RefreshTokens.Issue(ctx, tokens.Subject{UserID: "456"})
+	own, _ := deps.RefreshTokens.Issue(ctx, tokens.Subject{UserID: "123"})
+
+	// Le refresh token d'un autre utilisateur n'est pas révoqué
+	w, _ := postJSON(router, "/auth/logout", gin.H{"refresh_token": victim})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	_, _, err := deps.RefreshTokens.Rotate(ctx, victim)
+	assert.NoError(t, err)
+
+	w, _ = postJSON(router, "/auth/logout", gin.H{"refresh_token": own})
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, _, err = deps.RefreshTokens.Rotate(ctx, own)
+	assert.Error(t, err)
+}
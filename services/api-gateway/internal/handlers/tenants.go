@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+)
+
+type UpdateTenantMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ListTenantMembers liste les membres du tenant de l'appelant.
+func ListTenantMembers(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		members, err := deps.Tenants.List(c.Request.Context(), c.GetString("tenant_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list members"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"tenant_id": c.GetString("tenant_id"), "members": members})
+	}
+}
+
+// UpdateTenantMember change le rôle d'un membre. Ses tokens d'accès sont révoqués
+// pour que le nouveau rôle s'applique immédiatement : le refresh qui suit émet des
+// tokens avec le rôle du tenant.
+func UpdateTenantMember(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UpdateTenantMemberRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !checkNotSelf(c) {
+			return
+		}
+
+		ctx := c.Request.Context()
+		member, err := deps.Tenants.SetRole(ctx, c.GetString("tenant_id"), c.Param("user_id"), req.Role)
+		if !handleMemberError(c, err) {
+			return
+		}
+		if err := deps.Denylist.RevokeUser(ctx, member.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"member": member})
+	}
+}
+
+// RemoveTenantMember retire un membre du tenant et révoque tous ses tokens.
+func RemoveTenantMember(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkNotSelf(c) {
+			return
+		}
+
+		ctx := c.Request.Context()
+		member, err := deps.Tenants.Remove(ctx, c.GetString("tenant_id"), c.Param("user_id"))
+		if !handleMemberError(c, err) {
+			return
+		}
+		if err := deps.Denylist.RevokeUser(ctx, member.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+			return
+		}
+		if err := deps.RefreshTokens.RevokeUser(ctx, member.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh tokens"})
+			return
+		}
+		if deps.Sessions != nil {
+			if err := deps.Sessions.DeleteUser(ctx, member.UserID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sessions"})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Member removed", "member": member})
+	}
+}
+
+// RestoreTenantMember réintègre un membre retiré.
+func RestoreTenantMember(deps *AuthDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		member, err := deps.Tenants.Restore(c.Request.Context(), c.GetString("tenant_id"), c.Param("user_id"))
+		if !handleMemberError(c, err) {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"member": member})
+	}
+}
+
+// checkNotSelf empêche un admin de tenant de modifier sa propre appartenance.
+func checkNotSelf(c *gin.Context) bool {
+	if c.Param("user_id") == c.GetString("user_id") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot modify your own membership"})
+		return false
+	}
+	return true
+}
+
+func handleMemberError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, tenants.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return false
+	case errors.Is(err, tenants.ErrRoleNotAssignable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "roles": tenants.AssignableRoles})
+		return false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssueTokensAddsTenantClaim(t *testing.T) {
+	cfg := &config.Config{JWT_SECRET: "test-secret", AccessTokenTTL: time.Minute, DefaultTenant: "default"}
+	deps := newTestAuthDeps(cfg)
+
+	resp, err := issueTokens(newTestContext(), cfg, deps, tokens.Subject{UserID: "123", Username: "alice", Role: "user", TenantID: "acme"})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", resp["tenant_id"])
+
+	token, err := jwt.Parse(resp["token"].(string), deps.Keys.Keyfunc)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", token.Claims.(jwt.MapClaims)["tenant_id"])
+
+	// Sans tenant, l'utilisateur rejoint le tenant par défaut
+	resp, _ = issueTokens(newTestContext(), cfg, deps, tokens.Subject{UserID: "456", Username: "bob", Role: "user"})
+	assert.Equal(t, "default", resp["tenant_id"])
+}
+
+func TestTenantAdminManagesMembers(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWT_SECRET: "test-secret", AccessTokenTTL: time.Minute}
+	deps := newTestAuthDeps(cfg)
+
+	issueTokens(newTestContext(), cfg, deps, tokens.Subject{UserID: "admin-1", Username: "root", Role: "tenant_admin", TenantID: "acme"})
+	issueTokens(newTestContext(), cfg, deps, tokens.Subject{UserID: "user-1", Username: "alice", Role: "user", TenantID: "acme"})
+	issueTokens(newTestContext(), cfg, deps, tokens.Subject{UserID: "user-2", Username: "eve", Role: "user", TenantID: "other"})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "admin-1")
+		c.Set("tenant_id", "acme")
+		c.Next()
+	})
+	router.GET("/tenant/members", ListTenantMembers(deps))
+	router.PUT("/tenant/members/:user_id", UpdateTenantMember(deps))
+	router.DELETE("/tenant/members/:user_id", RemoveTenantMember(deps))
+
+	// Seuls les membres du tenant sont listés
+	req, _ := http.NewRequest("GET", "/tenant/members", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Members []map[string]interface{} `json:"members"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Len(t, resp.Members, 2)
+
+	// Changement de rôle : le rôle du tenant s'applique aux tokens suivants
+	req, _ = http.NewRequest("PUT", "/tenant/members/user-1", bytes.NewBufferString(`{"role": "readonly"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	tokensResp, err := issueTokens(newTestContext(), cfg, deps, tokens.Subject{UserID: "user-1", Username: "alice", Role: "user", TenantID: "acme"})
+	assert.NoError(t, err)
+	assert.Equal(t, "readonly", tokensResp["role"])
+
+	// Le rôle admin global ne peut pas être attribué
+	req, _ = http.NewRequest("PUT", "/tenant/members/user-1", bytes.NewBufferString(`{"role": "admin"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Un membre d'un autre tenant est introuvable
+	req, _ = http.NewRequest("DELETE", "/tenant/members/user-2", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Un membre retiré ne peut plus se connecter
+	req, _ = http.NewRequest("DELETE", "/tenant/members/user-1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	_, err = issueTokens(newTestContext(), cfg, deps, tokens.Subject{UserID: "user-1", Username: "alice", Role: "user", TenantID: "acme"})
+	assert.Error(t, err)
+}
+
+// newTestContext crée un contexte Gin pour appeler issueTokens hors d'une route.
+func newTestContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+	return c
+}
+
+func TestLoginResyncsAdminRole(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWT_SECRET: "test-secret", AccessTokenTTL: time.Minute, DefaultTenant: "acme"}
+	deps := newTestAuthDeps(cfg)
+	role := "admin"
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusOK, `{"user_id":"123","username":"alice","role":"`+role+`"}`), nil
+	})}
+	router := gin.New()
+	router.POST("/login", Login(cfg, deps, client))
+	login := gin.H{"username": "alice", "password": "secret"}
+
+	w, resp := postJSON(router, "/login", login)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "admin", resp["role"])
+
+	// Rétrogradé par le service d'authentification : le tenant ne garde pas le rôle admin
+	role = "user"
+	w, resp = postJSON(router, "/login", login)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user", resp["role"])
+
+	// Un rôle attribué dans le tenant est conservé
+	_, err := deps.Tenants.SetRole(newTestContext().Request.Context(), "acme", "123", "tenant_admin")
+	assert.NoError(t, err)
+	_, resp = postJSON(router, "/login", login)
+	assert.Equal(t, "tenant_admin", resp["role"])
+}
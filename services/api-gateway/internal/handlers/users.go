@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/audit"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/middleware"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/users"
+)
+
+type SetUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ListUsers liste les comptes de l'annuaire embarqué.
+func ListUsers(repo *users.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := repo.List(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+			return
+		}
+		if list == nil {
+			list = []users.User{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"users": list})
+	}
+}
+
+// SetUserRole attribue un rôle à un utilisateur de l'annuaire embarqué, ainsi que
+// dans son tenant. Ses tokens d'accès sont révoqués pour que le rôle s'applique
+// immédiatement ; une rétrogradation ferme aussi ses sessions.
+func SetUserRole(cfg *config.Config, deps *AuthDeps, repo *users.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SetUserRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, known := cfg.RolePermissions[req.Role]; !known {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+			return
+		}
+
+		ctx := c.Request.Context()
+		previous, err := repo.Get(ctx, c.Param("user_id"))
+		if errors.Is(err, users.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+			return
+		}
+		user, err := repo.SetRole(ctx, previous.ID, req.Role)
+		if errors.Is(err, users.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+			return
+		}
+
+		// Un membre existant garde le rôle de son tenant : le mettre à jour aussi
+		if deps.Tenants != nil {
+			tenantID := tenants.Resolve(user.TenantID, cfg.DefaultTenant)
+			_, err := deps.Tenants.AssignRole(ctx, tenantID, user.ID, req.Role)
+			if err != nil && !errors.Is(err, tenants.ErrNotFound) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tenant role"})
+				return
+			}
+		}
+		// Les tokens en cours portent l'ancien rôle. Après une rétrogradation, les
+		// refresh tokens aussi : hors tenant, un refresh rétablirait l'ancien rôle
+		if demoted(cfg, previous.Role, req.Role) {
+			err = endAllSessions(ctx, deps, user.ID)
+		} else {
+			err = deps.Denylist.RevokeUser(ctx, user.ID)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+			return
+		}
+		if deps.Audit != nil {
+			deps.Audit.Log(ctx, audit.Entry{
+				Action:   "user.role_changed",
+				Actor:    c.GetString("username"),
+				Target:   user.ID,
+				TenantID: user.TenantID,
+				IP:       c.ClientIP(),
+				Data:     map[string]interface{}{"role": req.Role},
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"user": user})
+	}
+}
+
+// demoted indique que le nouveau rôle perd au moins une permission de l'ancien.
+func demoted(cfg *config.Config, from, to string) bool {
+	permissions := middleware.Permissions(cfg.RolePermissions)
+	for _, permission := range cfg.RolePermissions[from] {
+		if !permissions.Allows(to, permission) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/accounts"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/mailer"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/users"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedProviderRegisterAndLogin(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		JWT_SECRET:      "test-secret",
+		AccessTokenTTL:  time.Minute,
+		DefaultTenant:   "default",
+		RolePermissions: map[string][]string{"admin": {"*"}, "user": {"files:read"}},
+	}
+	hasher, err := users.NewHasher(users.Bcrypt)
+	require.NoError(t, err)
+	repo, err := users.Open(context.Background(), users.SQLite, ":memory:", hasher)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	st := store.NewMemory()
+	deps := newTestAuthDeps(cfg)
+	deps.Provider = NewEmbeddedProvider(repo, "user")
+	deps.Registration = &validation.Rules{PasswordMinLength: 10}
+	deps.Accounts = accounts.NewRepository(st)
+	deps.ActionTokens = tokens.NewActionTokens(st, cfg.JWT_SECRET)
+	deps.Mailer = mailer.NewFile("", "no-reply@example.com")
+
+	// Aucun service distant ne doit être appelé
+	client := &http.Client{Transport: &MockRoundTripper{Error: fmt.Errorf("unexpected call")}}
+	router := gin.New()
+	router.POST("/register", Register(cfg, deps, notify.Multi(), client))
+	router.POST("/login", Login(cfg, deps, client))
+	router.PUT("/admin/users/:user_id/role", SetUserRole(cfg, deps, repo))
+
+	w, resp := postJSON(router, "/register", gin.H{"username": "alice", "email": "alice@example.com", "password": "Str0ng-Passw0rd"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	userID := resp["user_id"].(string)
+
+	// Nom déjà pris
+	w, resp = postJSON(router, "/register", gin.H{"username": "Alice", "email": "other@example.com", "password": "Str0ng-Passw0rd"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, fmt.Sprint(resp["fields"]), "taken")
+
+	w, _ = postJSON(router, "/login", gin.H{"username": "alice", "password": "wrong-password"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w, resp = postJSON(router, "/login", gin.H{"username": "alice", "password": "Str0ng-Passw0rd"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, userID, resp["user_id"])
+	assert.Equal(t, "user", resp["role"])
+	assert.Equal(t, "default", resp["tenant_id"])
+
+	// Un admin attribue un rôle : il s'applique aussi dans le tenant
+	setRole := func(id, role string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(gin.H{"role": role})
+		req, _ := http.NewRequest("PUT", "/admin/users/"+id+"/role", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusBadRequest, setRole(userID, "superuser").Code)
+	assert.Equal(t, http.StatusNotFound, setRole("unknown", "admin").Code)
+	require.Equal(t, http.StatusOK, setRole(userID, "admin").Code)
+
+	member, err := deps.Tenants.Get(context.Background(), "default", userID)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", member.Role)
+	w, resp = postJSON(router, "/login", gin.H{"username": "alice", "password": "Str0ng-Passw0rd"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "admin", resp["role"])
+	refreshToken := resp["refresh_token"].(string)
+
+	// Rétrogradation : les tokens d'accès et les sessions de l'admin sont révoqués
+	time.Sleep(2 * time.Millisecond)
+	require.Equal(t, http.StatusOK, setRole(userID, "user").Code)
+	revoked, _ := deps.Denylist.IsRevoked(context.Background(), "", userID, time.Now().Add(-time.Millisecond))
+	assert.True(t, revoked)
+	_, _, err = deps.RefreshTokens.Rotate(context.Background(), refreshToken)
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/netguard"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/webhooks"
+)
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required,min=1"`
+	// Global abonne l'endpoint aux évènements de tous les utilisateurs du tenant (admin seulement)
+	Global bool `json:"global"`
+}
+
+// CreateWebhook enregistre un endpoint. Le secret de signature n'est retourné qu'ici.
+// Un admin peut demander un endpoint global, qui reçoit les évènements de tous les
+// utilisateurs du tenant.
+// Les URL vers un réseau interne (privé, loopback, link-local) sont refusées.
+func CreateWebhook(repo *webhooks.Repository, guard *netguard.Guard) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateWebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		u, err := url.Parse(req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http or https url"})
+			return
+		}
+		if err := guard.CheckHost(c.Request.Context(), u.Hostname()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url must point to a public address"})
+			return
+		}
+
+		if req.Global && c.GetString("role") != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can create global webhooks"})
+			return
+		}
+
+		endpoint := &webhooks.Endpoint{
+			UserID:   c.GetString("user_id"),
+			TenantID: c.GetString("tenant_id"),
+			URL:      u.String(),
+			Events:   req.Events,
+			Global:   req.Global,
+		}
+		if err := repo.Create(c.Request.Context(), endpoint); err != nil {
+			if errors.Is(err, webhooks.ErrUnknownEvent) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "events": webhooks.Events})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"webhook": endpoint})
+	}
+}
+
+// ListWebhooks liste les endpoints de l'utilisateur, sans leurs secrets.
+func ListWebhooks(repo *webhooks.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		endpoints, err := repo.List(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhooks"})
+			return
+		}
+		for i := range endpoints {
+			endpoints[i].Secret = ""
+		}
+
+		c.JSON(http.StatusOK, gin.H{"webhooks": endpoints})
+	}
+}
+
+// DeleteWebhook supprime un endpoint de l'utilisateur.
+func DeleteWebhook(repo *webhooks.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := repo.Delete(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+		if errors.Is(err, webhooks.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+	}
+}
+
+// ListWebhookDeliveries retourne le journal des tentatives de livraison d'un endpoint.
+func ListWebhookDeliveries(repo *webhooks.Repository, dispatcher *webhooks.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		endpoint, err := repo.Get(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+		if errors.Is(err, webhooks.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load webhook"})
+			return
+		}
+
+		attempts, err := dispatcher.Deliveries(c.Request.Context(), endpoint.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list deliveries"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"deliveries": attempts})
+	}
+}
+
+// ListWebhookDeadLetters retourne les livraisons abandonnées de l'utilisateur.
+func ListWebhookDeadLetters(dispatcher *webhooks.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		letters, err := dispatcher.DeadLetters(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list dead letters"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"dead_letters": letters})
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/netguard"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/webhooks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	guard, _ := netguard.New(nil)
+	repo := webhooks.NewRepository(store.NewMemory())
+	role := "tenant_admin"
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "user-1")
+		c.Set("tenant_id", "acme")
+		c.Set("role", role)
+		c.Next()
+	})
+	router.POST("/webhooks", CreateWebhook(repo, guard))
+
+	// Adresses internes refusées
+	for _, target := range []string{"http://127.0.0.1:6379", "http://10.0.0.5/hook", "http://169.254.169.254/latest/meta-data"} {
+		w, _ := postJSON(router, "/webhooks", gin.H{"url": target, "events": []string{"*"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+
+	// Endpoint global : opt-in réservé aux admins
+	w, resp := postJSON(router, "/webhooks", gin.H{"url": "https://93.184.216.34/hook", "events": []string{"*"}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Nil(t, resp["webhook"].(map[string]interface{})["global"])
+	w, _ = postJSON(router, "/webhooks", gin.H{"url": "https://93.184.216.34/hook", "events": []string{"*"}, "global": true})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	role = "admin"
+	w, resp = postJSON(router, "/webhooks", gin.H{"url": "https://93.184.216.34/hook", "events": []string{"*"}, "global": true})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, true, resp["webhook"].(map[string]interface{})["global"])
+}
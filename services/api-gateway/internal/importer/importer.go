@@ -0,0 +1,237 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/jobs"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/netguard"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/quota"
+)
+
+const (
+	// Nombre d'imports exécutés en parallèle
+	maxConcurrentImports = 4
+	// Nombre maximal de redirections suivies
+	maxRedirects = 5
+	// Fréquence de mise à jour de la progression
+	progressInterval = time.Second
+)
+
+// ErrInvalidURL est retourné quand l'URL source n'est pas une URL http(s) valide.
+var ErrInvalidURL = errors.New("source url must be an absolute http or https url")
+
+// ErrBlockedAddress est retourné quand l'URL pointe vers un réseau interdit.
+var ErrBlockedAddress = netguard.ErrBlockedAddress
+
+// ErrTooLarge est retourné quand la source dépasse la taille maximale autorisée.
+var ErrTooLarge = errors.New("source exceeds maximum import size")
+
+// Options configure l'importeur.
+type Options struct {
+	MaxSize   int64
+	Timeout   time.Duration
+	Allowlist []string
+}
+
+// Importer télécharge des fichiers depuis une URL en tâche de fond.
+type Importer struct {
+	jobs     *jobs.Repository
+	quotas   *quota.Tracker
+	notifier notify.Notifier
+	client   *http.Client
+	maxSize  int64
+	timeout  time.Duration
+	slots    chan struct{}
+}
+
+// New crée un importeur dont le client HTTP refuse les adresses internes.
+func New(opts Options, jobRepo *jobs.Repository, quotas *quota.Tracker, notifier notify.Notifier) (*Importer, error) {
+	guard, err := netguard.New(opts.Allowlist)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Transport: guard.Transport(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrInvalidURL
+			}
+			return nil
+		},
+	}
+
+	return &Importer{
+		jobs:     jobRepo,
+		quotas:   quotas,
+		notifier: notifier,
+		client:   client,
+		maxSize:  opts.MaxSize,
+		timeout:  opts.Timeout,
+		slots:    make(chan struct{}, maxConcurrentImports),
+	}, nil
+}
+
+// Start valide l'URL, crée le job et lance le téléchargement en tâche de fond.
+func (i *Importer) Start(ctx context.Context, tenantID, userID, sourceURL string) (*jobs.Job, error) {
+	u, err := url.Parse(sourceURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	job := &jobs.Job{
+		Type:     "import",
+		UserID:   userID,
+		TenantID: tenantID,
+		Source:   u.String(),
+	}
+	if err := i.jobs.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	go i.run(*job)
+
+	return job, nil
+}
+
+func (i *Importer) run(job jobs.Job) {
+	i.slots <- struct{}{}
+	defer func() { <-i.slots }()
+
+	ctx := context.Background()
+	if i.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.timeout)
+		defer cancel()
+	}
+
+	job.Status = jobs.StatusRunning
+	i.save(&job)
+
+	result, err := i.fetch(ctx, &job)
+	if err != nil {
+		job.Status = jobs.StatusFailed
+		job.Error = err.Error()
+		i.save(&job)
+		i.notify(job, "import.failed")
+		return
+	}
+
+	job.Status = jobs.StatusCompleted
+	job.Result = result
+	i.save(&job)
+	i.notify(job, "import.completed")
+}
+
+// fetch télécharge la source en respectant la taille maximale et le quota.
+func (i *Importer) fetch(ctx context.Context, job *jobs.Job) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, job.Source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "mini-cloud-importer/1.0")
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			return nil, ErrBlockedAddress
+		}
+		return nil, fmt.Errorf("failed to fetch source: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("source returned non-200 status: %s", resp.Status)
+	}
+	if i.maxSize > 0 && resp.ContentLength > i.maxSize {
+		return nil, ErrTooLarge
+	}
+	if resp.ContentLength > 0 {
+		job.BytesTotal = resp.ContentLength
+	}
+
+	// Lire au plus maxSize+1 octets pour détecter les dépassements
+	var body io.Reader = resp.Body
+	if i.maxSize > 0 {
+		body = io.LimitReader(resp.Body, i.maxSize+1)
+	}
+
+	hash := sha256.New()
+	progress := &progressWriter{importer: i, job: job, lastSave: time.Now()}
+	size, err := io.Copy(io.MultiWriter(hash, progress), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source: %v", err)
+	}
+	if i.maxSize > 0 && size > i.maxSize {
+		return nil, ErrTooLarge
+	}
+
+	if err := i.quotas.Reserve(ctx, job.TenantID, job.UserID, size); err != nil {
+		return nil, err
+	}
+
+	// TODO: Envoyer le contenu au service de fichiers
+	return map[string]interface{}{
+		"filename":     filenameFromURL(resp.Request.URL),
+		"size":         size,
+		"content_type": resp.Header.Get("Content-Type"),
+		"sha256":       hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+func (i *Importer) save(job *jobs.Job) {
+	if err := i.jobs.Save(context.Background(), job); err != nil {
+		log.Printf("importer: failed to save job %s: %v", job.ID, err)
+	}
+}
+
+func (i *Importer) notify(job jobs.Job, eventType string) {
+	i.notifier.Notify(context.Background(), notify.Event{
+		Type:     eventType,
+		UserID:   job.UserID,
+		TenantID: job.TenantID,
+		Data: map[string]interface{}{
+			"job_id": job.ID,
+			"source": job.Source,
+			"error":  job.Error,
+		},
+	})
+}
+
+// progressWriter compte les octets reçus et sauvegarde la progression périodiquement.
+type progressWriter struct {
+	importer *Importer
+	job      *jobs.Job
+	lastSave time.Time
+}
+
+func (p *progressWriter) Write(data []byte) (int, error) {
+	p.job.BytesDone += int64(len(data))
+	if time.Since(p.lastSave) >= progressInterval {
+		p.importer.save(p.job)
+		p.lastSave = time.Now()
+	}
+	return len(data), nil
+}
+
+func filenameFromURL(u *url.URL) string {
+	name := path.Base(u.Path)
+	if name == "." || name == "/" || name == "" {
+		return "download"
+	}
+	return name
+}
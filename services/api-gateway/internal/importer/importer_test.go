@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/jobs"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/quota"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestImporter(t *testing.T, opts Options) (*Importer, *jobs.Repository) {
+	st := store.NewMemory()
+	jobRepo := jobs.NewRepository(st)
+	imp, err := New(opts, jobRepo, quota.New(st, 0), notify.NewInbox(st))
+	assert.NoError(t, err)
+	return imp, jobRepo
+}
+
+func waitForJob(t *testing.T, jobRepo *jobs.Repository, id string) *jobs.Job {
+	var job *jobs.Job
+	assert.Eventually(t, func() bool {
+		job, _ = jobRepo.Get(context.Background(), "user-1", id)
+		return job != nil && (job.Status == jobs.StatusCompleted || job.Status == jobs.StatusFailed)
+	}, 2*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestImportAllowlisted(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("a,b\n1,2\n"))
+	}))
+	defer source.Close()
+
+	imp, jobRepo := newTestImporter(t, Options{MaxSize: 1024, Timeout: time.Second, Allowlist: []string{"127.0.0.1"}})
+
+	job, err := imp.Start(context.Background(), "tenant-1", "user-1", source.URL+"/data.csv")
+	assert.NoError(t, err)
+
+	job = waitForJob(t, jobRepo, job.ID)
+	assert.Equal(t, jobs.StatusCompleted, job.Status)
+	assert.Equal(t, int64(8), job.BytesDone)
+	assert.Equal(t, "data.csv", job.Result["filename"])
+}
+
+func TestImportBlocksLoopback(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer source.Close()
+
+	imp, jobRepo := newTestImporter(t, Options{MaxSize: 1024, Timeout: time.Second})
+
+	job, err := imp.Start(context.Background(), "tenant-1", "user-1", source.URL)
+	assert.NoError(t, err)
+
+	job = waitForJob(t, jobRepo, job.ID)
+	assert.Equal(t, jobs.StatusFailed, job.Status)
+	assert.Equal(t, ErrBlockedAddress.Error(), job.Error)
+}
+
+func TestImportTooLarge(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 2048)))
+	}))
+	defer source.Close()
+
+	imp, jobRepo := newTestImporter(t, Options{MaxSize: 1024, Timeout: time.Second, Allowlist: []string{"127.0.0.0/8"}})
+
+	job, _ := imp.Start(context.Background(), "tenant-1", "user-1", source.URL)
+
+	job = waitForJob(t, jobRepo, job.ID)
+	assert.Equal(t, jobs.StatusFailed, job.Status)
+	assert.Equal(t, ErrTooLarge.Error(), job.Error)
+}
+
+func TestImportRejectsInvalidURL(t *testing.T) {
+	imp, _ := newTestImporter(t, Options{})
+
+	_, err := imp.Start(context.Background(), "tenant-1", "user-1", "file:///etc/passwd")
+	assert.ErrorIs(t, err, ErrInvalidURL)
+}
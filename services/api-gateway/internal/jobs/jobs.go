@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// Durée de conservation du statut d'un job
+const jobTTL = 7 * 24 * time.Hour
+
+// ErrNotFound est retourné quand le job n'existe pas ou n'appartient pas à l'utilisateur.
+var ErrNotFound = errors.New("job not found")
+
+// Statuts possibles d'un job
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Job décrit une tâche de fond et son avancement.
+type Job struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	UserID     string                 `json:"user_id"`
+	TenantID   string                 `json:"tenant_id"`
+	Status     string                 `json:"status"`
+	Source     string                 `json:"source,omitempty"`
+	BytesDone  int64                  `json:"bytes_done"`
+	BytesTotal int64                  `json:"bytes_total,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Result     map[string]interface{} `json:"result,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+// Repository persiste l'état des jobs dans le store.
+type Repository struct {
+	store store.Store
+}
+
+// NewRepository crée un repository adossé au store.
+func NewRepository(st store.Store) *Repository {
+	return &Repository{store: st}
+}
+
+func jobKey(id string) string {
+	return fmt.Sprintf("job:%s", id)
+}
+
+// Create enregistre un nouveau job en attente.
+func (r *Repository) Create(ctx context.Context, job *Job) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	job.ID = id
+	job.Status = StatusPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	return store.SetJSON(ctx, r.store, jobKey(id), job, jobTTL)
+}
+
+// Save met à jour l'état d'un job existant.
+func (r *Repository) Save(ctx context.Context, job *Job) error {
+	job.UpdatedAt = time.Now().UTC()
+	return store.SetJSON(ctx, r.store, jobKey(job.ID), job, jobTTL)
+}
+
+// Get retourne un job appartenant à userID.
+func (r *Repository) Get(ctx context.Context, userID, id string) (*Job, error) {
+	var job Job
+	if err := store.GetJSON(ctx, r.store, jobKey(id), &job); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return &job, nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
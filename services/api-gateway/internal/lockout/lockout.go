@@ -0,0 +1,305 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/audit"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// ErrLocked est retourné quand le compte ou l'IP est verrouillé, ou qu'un délai
+// progressif est en cours.
+var ErrLocked = errors.New("login temporarily locked")
+
+// Options configure la protection contre la force brute.
+type Options struct {
+	MaxFailures     int           // échecs par nom d'utilisateur avant verrouillage
+	MaxIPFailures   int           // échecs par IP avant verrouillage
+	Window          time.Duration // fenêtre de comptage des échecs
+	LockoutDuration time.Duration
+	BaseDelay       time.Duration // délai après le premier échec, doublé à chaque échec
+	MaxDelay        time.Duration
+}
+
+// Guard compte les échecs de connexion par nom d'utilisateur et par IP.
+// Les compteurs existent aussi pour les utilisateurs inconnus : la réponse ne
+// révèle pas si le compte existe.
+type Guard struct {
+	store   store.Store
+	options Options
+	audit   audit.Logger
+}
+
+// NewGuard crée une protection adossée au store. Les verrouillages sont journalisés.
+func NewGuard(st store.Store, options Options, auditLog audit.Logger) *Guard {
+	return &Guard{store: st, options: options, audit: auditLog}
+}
+
+func userTarget(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipTarget(ip string) string {
+	return "ip:" + ip
+}
+
+func failuresKey(target string) string {
+	return fmt.Sprintf("login_failures:%s", target)
+}
+
+func lockedKey(target string) string {
+	return fmt.Sprintf("login_locked:%s", target)
+}
+
+func delayKey(target string) string {
+	return fmt.Sprintf("login_delay:%s", target)
+}
+
+// Check indique si une tentative est autorisée et la réserve : l'échec est compté
+// avant la vérification du mot de passe, pour que des tentatives concurrentes ne
+// dépassent pas le seuil. En cas de refus, retourne ErrLocked et le temps à attendre.
+func (g *Guard) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	for _, key := range []string{
+		lockedKey(ipTarget(ip)),
+		lockedKey(userTarget(username)),
+		delayKey(userTarget(username)),
+	} {
+		retryAfter, err := g.remaining(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if retryAfter > 0 {
+			return retryAfter, ErrLocked
+		}
+	}
+
+	for _, limit := range []struct {
+		target string
+		max    int
+	}{
+		{ipTarget(ip), g.options.MaxIPFailures},
+		{userTarget(username), g.options.MaxFailures},
+	} {
+		count, err := g.increment(ctx, limit.target)
+		if err != nil {
+			return 0, err
+		}
+		// Seuil déjà atteint par des tentatives en cours
+		if limit.max > 0 && count > int64(limit.max) {
+			if err := g.lock(ctx, limit.target, ip, count); err != nil {
+				return 0, err
+			}
+			return g.options.LockoutDuration, ErrLocked
+		}
+	}
+	return 0, nil
+}
+
+// Fail confirme l'échec réservé par Check et retourne le temps à attendre avant la
+// prochaine tentative.
+func (g *Guard) Fail(ctx context.Context, username, ip string) (time.Duration, error) {
+	user := userTarget(username)
+	userFailures, err := g.failures(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+	ipFailures, err := g.failures(ctx, ipTarget(ip))
+	if err != nil {
+		return 0, err
+	}
+
+	if g.options.MaxIPFailures > 0 && ipFailures >= int64(g.options.MaxIPFailures) {
+		if err := g.lock(ctx, ipTarget(ip), ip, ipFailures); err != nil {
+			return 0, err
+		}
+	}
+	if g.options.MaxFailures > 0 && userFailures >= int64(g.options.MaxFailures) {
+		if err := g.lock(ctx, user, ip, userFailures); err != nil {
+			return 0, err
+		}
+		return g.options.LockoutDuration, nil
+	}
+
+	delay := g.delay(userFailures)
+	if delay > 0 {
+		until := time.Now().Add(delay)
+		if err := g.store.Set(ctx, delayKey(user), until.Format(time.RFC3339Nano), delay); err != nil {
+			return 0, err
+		}
+	}
+	return delay, nil
+}
+
+// Succeed remet à zéro les compteurs de l'utilisateur. Pour l'IP, seule la tentative
+// réservée par Check est rendue (ip vide hors connexion) : un compte valide ne doit
+// pas permettre d'en attaquer d'autres.
+func (g *Guard) Succeed(ctx context.Context, username, ip string) error {
+	user := userTarget(username)
+	if err := g.store.Delete(ctx, failuresKey(user), delayKey(user)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return g.release(ctx, ipTarget(ip))
+}
+
+// Release rend la tentative réservée par Check sans remettre les compteurs à zéro :
+// la tentative n'a pas abouti, sans être un échec (mot de passe vérifié en attente
+// du second facteur, fournisseur indisponible...).
+func (g *Guard) Release(ctx context.Context, username, ip string) error {
+	if err := g.release(ctx, userTarget(username)); err != nil {
+		return err
+	}
+	return g.release(ctx, ipTarget(ip))
+}
+
+// Unlock lève le verrouillage d'un utilisateur ou d'une IP (action d'administration).
+func (g *Guard) Unlock(ctx context.Context, actor, username, ip string) error {
+	var targets []string
+	if username != "" {
+		targets = append(targets, userTarget(username))
+	}
+	if ip != "" {
+		targets = append(targets, ipTarget(ip))
+	}
+	for _, target := range targets {
+		if err := g.store.Delete(ctx, lockedKey(target), failuresKey(target), delayKey(target)); err != nil {
+			return err
+		}
+		g.record(ctx, audit.Entry{Action: "login.unlocked", Actor: actor, Target: target})
+	}
+	return nil
+}
+
+// release décrémente le compteur d'échecs de target.
+func (g *Guard) release(ctx context.Context, target string) error {
+	count, err := g.store.IncrBy(ctx, failuresKey(target), -1)
+	if err != nil {
+		return err
+	}
+	// Compteur expiré entre-temps : ne pas laisser de valeur sans expiration
+	if count <= 0 {
+		return g.store.Delete(ctx, failuresKey(target))
+	}
+	return nil
+}
+
+func (g *Guard) increment(ctx context.Context, target string) (int64, error) {
+	count, err := g.store.IncrBy(ctx, failuresKey(target), 1)
+	if err != nil {
+		return 0, err
+	}
+	// Premier échec de la fenêtre : définir l'expiration du compteur
+	if count == 1 {
+		if err := g.store.Expire(ctx, failuresKey(target), g.options.Window); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// failures lit le compteur d'échecs de la fenêtre en cours.
+func (g *Guard) failures(ctx context.Context, target string) (int64, error) {
+	value, err := g.store.Get(ctx, failuresKey(target))
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func (g *Guard) lock(ctx context.Context, target, ip string, failures int64) error {
+	until := time.Now().Add(g.options.LockoutDuration)
+	if err := g.store.Set(ctx, lockedKey(target), until.Format(time.RFC3339Nano), g.options.LockoutDuration); err != nil {
+		return err
+	}
+	// Le compteur repart de zéro après le verrouillage
+	if err := g.store.Delete(ctx, failuresKey(target)); err != nil {
+		return err
+	}
+	g.record(ctx, audit.Entry{
+		Action: "login.locked",
+		Target: target,
+		IP:     ip,
+		Data:   map[string]interface{}{"failures": failures, "until": until.UTC()},
+	})
+	return nil
+}
+
+// delay retourne le délai progressif après n échecs : BaseDelay, puis doublé, plafonné à MaxDelay.
+func (g *Guard) delay(failures int64) time.Duration {
+	if g.options.BaseDelay <= 0 || failures <= 0 {
+		return 0
+	}
+	delay := g.options.BaseDelay
+	for i := int64(1); i < failures; i++ {
+		delay *= 2
+		if g.options.MaxDelay > 0 && delay >= g.options.MaxDelay {
+			return g.options.MaxDelay
+		}
+	}
+	return delay
+}
+
+// remaining lit une échéance enregistrée sous key et retourne le temps restant.
+func (g *Guard) remaining(ctx context.Context, key string) (time.Duration, error) {
+	value, err := g.store.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	until, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, nil
+	}
+	return time.Until(until), nil
+}
+
+func (g *Guard) record(ctx context.Context, entry audit.Entry) {
+	if g.audit == nil {
+		return
+	}
+	// Best effort : l'audit ne doit pas bloquer la connexion
+	g.audit.Log(ctx, entry)
+}
+
+// Throttle limite le nombre d'opérations par clé (IP, adresse email...) sur une
+// fenêtre glissante à partir de la première.
+type Throttle struct {
+	store  store.Store
+	limit  int
+	window time.Duration
+}
+
+// NewThrottle autorise limit opérations par fenêtre ; limit <= 0 désactive la limite.
+func NewThrottle(st store.Store, limit int, window time.Duration) *Throttle {
+	return &Throttle{store: st, limit: limit, window: window}
+}
+
+// Allow compte une opération pour key et indique si elle reste sous la limite.
+func (t *Throttle) Allow(ctx context.Context, key string) (bool, error) {
+	if t == nil || t.limit <= 0 {
+		return true, nil
+	}
+	counter := fmt.Sprintf("throttle:%s", key)
+	count, err := t.store.IncrBy(ctx, counter, 1)
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		if err := t.store.Expire(ctx, counter, t.window); err != nil {
+			return false, err
+		}
+	}
+	return count <= int64(t.limit), nil
+}
@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message est un email texte.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer envoie des emails.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPOptions configure l'envoi par SMTP.
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string // vide = pas d'authentification
+	Password string
+	From     string
+}
+
+// SMTP envoie les emails par un serveur SMTP (STARTTLS si le serveur le propose).
+type SMTP struct {
+	options SMTPOptions
+}
+
+// NewSMTP crée un mailer SMTP.
+func NewSMTP(options SMTPOptions) *SMTP {
+	return &SMTP{options: options}
+}
+
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.options.Host, fmt.Sprint(m.options.Port))
+	var auth smtp.Auth
+	if m.options.Username != "" {
+		auth = smtp.PlainAuth("", m.options.Username, m.options.Password, m.options.Host)
+	}
+	return smtp.SendMail(addr, auth, m.options.From, []string{msg.To}, format(m.options.From, msg))
+}
+
+// File écrit chaque email dans un fichier .eml du répertoire donné, ou dans les logs
+// si aucun répertoire n'est configuré. Pour le développement et les tests.
+type File struct {
+	dir  string
+	from string
+}
+
+// NewFile crée un mailer fichier ; dir vide écrit les emails dans les logs.
+func NewFile(dir, from string) *File {
+	return &File{dir: dir, from: from}
+}
+
+func (m *File) Send(ctx context.Context, msg Message) error {
+	data := format(m.from, msg)
+	if m.dir == "" {
+		log.Printf("mail to %s:\n%s", msg.To, data)
+		return nil
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitize(msg.To))
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o600)
+}
+
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
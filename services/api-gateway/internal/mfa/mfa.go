@@ -0,0 +1,450 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+)
+
+// Nombre de codes de secours générés à l'activation
+const recoveryCodeCount = 10
+
+// Nombre d'essais autorisés par challenge de connexion
+const maxChallengeAttempts = 5
+
+// Essais de code autorisés par utilisateur pour gérer son MFA (activation, codes
+// de secours, désactivation) sur la fenêtre manageAttemptWindow
+const (
+	maxManageAttempts   = 5
+	manageAttemptWindow = 15 * time.Minute
+)
+
+// Durée de validité d'un code TOTP, dérive d'horloge comprise : un compteur réclamé
+// n'a pas besoin d'être retenu plus longtemps
+const usedCounterTTL = (2*totpSkew + 1) * totpPeriod * time.Second
+
+var (
+	// ErrNotEnrolled est retourné quand l'utilisateur n'a pas démarré d'enrôlement.
+	ErrNotEnrolled = errors.New("mfa not enrolled")
+	// ErrAlreadyEnabled est retourné quand le MFA est déjà actif.
+	ErrAlreadyEnabled = errors.New("mfa already enabled")
+	// ErrInvalidCode est retourné pour un code TOTP ou de secours invalide (ou rejoué).
+	ErrInvalidCode = errors.New("invalid mfa code")
+	// ErrInvalidChallenge est retourné pour un challenge inconnu, expiré ou épuisé.
+	ErrInvalidChallenge = errors.New("invalid or expired mfa challenge")
+	// ErrRequired est retourné quand le rôle de l'utilisateur impose le MFA.
+	ErrRequired = errors.New("mfa is required for this role")
+	// ErrTooManyAttempts est retourné quand l'utilisateur a épuisé ses essais de code.
+	ErrTooManyAttempts = errors.New("too many mfa attempts")
+)
+
+// Enrollment est l'état MFA d'un utilisateur. Les codes de secours sont stockés hashés.
+type Enrollment struct {
+	UserID        string     `json:"user_id"`
+	Secret        string     `json:"secret"`
+	Enabled       bool       `json:"enabled"`
+	RecoveryCodes []string   `json:"recovery_codes"`
+	LastCounter   uint64     `json:"last_counter"`
+	EnabledAt     *time.Time `json:"enabled_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Challenge est l'étape intermédiaire d'une connexion : mot de passe vérifié,
+// code MFA attendu.
+type Challenge struct {
+	Subject  tokens.Subject `json:"subject"`
+	Attempts int            `json:"attempts"`
+	// Enrollment indique que l'utilisateur doit d'abord enrôler un appareil
+	Enrollment bool      `json:"enrollment,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Service gère l'enrôlement TOTP, les codes de secours et les challenges de connexion.
+type Service struct {
+	store         store.Store
+	issuer        string
+	challengeTTL  time.Duration
+	requiredRoles []string
+}
+
+// NewService crée le service. requiredRoles est la liste par défaut des rôles
+// pour lesquels le MFA est obligatoire ; un admin peut la modifier.
+func NewService(st store.Store, issuer string, challengeTTL time.Duration, requiredRoles []string) *Service {
+	return &Service{store: st, issuer: issuer, challengeTTL: challengeTTL, requiredRoles: requiredRoles}
+}
+
+const requiredRolesKey = "mfa:required_roles"
+
+func enrollmentKey(userID string) string {
+	return fmt.Sprintf("mfa:enrollment:%s", userID)
+}
+
+func challengeKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("mfa:challenge:%s", hex.EncodeToString(sum[:]))
+}
+
+// Compteur d'essais du challenge, incrémenté atomiquement
+func challengeAttemptsKey(token string) string {
+	return challengeKey(token) + ":attempts"
+}
+
+func manageAttemptsKey(userID string) string {
+	return fmt.Sprintf("mfa:manage_attempts:%s", userID)
+}
+
+// Marqueurs d'usage unique, réclamés avec SetNX : deux requêtes concurrentes ne
+// peuvent pas accepter le même code
+func usedCounterKey(userID string, counter uint64) string {
+	return fmt.Sprintf("mfa:used_counter:%s:%d", userID, counter)
+}
+
+func usedRecoveryKey(userID, hash string) string {
+	return fmt.Sprintf("mfa:used_recovery:%s:%s", userID, hash)
+}
+
+// Begin démarre (ou recommence) l'enrôlement et retourne le secret et l'URI de provisioning.
+func (s *Service) Begin(ctx context.Context, userID, account string) (secret, uri string, err error) {
+	current, err := s.enrollment(ctx, userID)
+	if err != nil && !errors.Is(err, ErrNotEnrolled) {
+		return "", "", err
+	}
+	if current != nil && current.Enabled {
+		return "", "", ErrAlreadyEnabled
+	}
+
+	secret, err = GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	enrollment := &Enrollment{UserID: userID, Secret: secret, CreatedAt: time.Now().UTC()}
+	if err := s.save(ctx, enrollment); err != nil {
+		return "", "", err
+	}
+	return secret, ProvisioningURI(s.issuer, account, secret), nil
+}
+
+// Confirm active le MFA après vérification d'un premier code et retourne les codes
+// de secours. Les essais sont limités par utilisateur.
+func (s *Service) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	if err := s.countAttempt(ctx, userID); err != nil {
+		return nil, err
+	}
+	codes, err := s.confirm(ctx, userID, code)
+	if err != nil {
+		return nil, err
+	}
+	s.resetAttempts(ctx, userID)
+	return codes, nil
+}
+
+func (s *Service) confirm(ctx context.Context, userID, code string) ([]string, error) {
+	enrollment, err := s.enrollment(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+	counter, ok := validate(enrollment.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	if err := s.claim(ctx, usedCounterKey(userID, counter), usedCounterTTL); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	enrollment.Enabled = true
+	enrollment.EnabledAt = &now
+	enrollment.LastCounter = counter
+	enrollment.RecoveryCodes = hashes
+	return codes, s.save(ctx, enrollment)
+}
+
+// Enabled indique si l'utilisateur a activé le MFA.
+func (s *Service) Enabled(ctx context.Context, userID string) (bool, error) {
+	enrollment, err := s.enrollment(ctx, userID)
+	if errors.Is(err, ErrNotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return enrollment.Enabled, nil
+}
+
+// Verify vérifie un code TOTP (un code déjà utilisé est refusé) ou consomme un code de secours.
+// Le compteur TOTP ou le code de secours est réclamé avant l'écriture de l'enrôlement.
+func (s *Service) Verify(ctx context.Context, userID, code string) error {
+	enrollment, err := s.enrollment(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enrollment.Enabled {
+		return ErrNotEnrolled
+	}
+
+	if counter, ok := validate(enrollment.Secret, code, time.Now()); ok {
+		if counter <= enrollment.LastCounter {
+			return ErrInvalidCode
+		}
+		if err := s.claim(ctx, usedCounterKey(userID, counter), usedCounterTTL); err != nil {
+			return err
+		}
+		enrollment.LastCounter = counter
+		return s.save(ctx, enrollment)
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, stored := range enrollment.RecoveryCodes {
+		if stored == hash {
+			// Sans expiration : une écriture concurrente de l'enrôlement peut encore
+			// contenir le code consommé
+			if err := s.claim(ctx, usedRecoveryKey(userID, hash), 0); err != nil {
+				return err
+			}
+			enrollment.RecoveryCodes = append(enrollment.RecoveryCodes[:i], enrollment.RecoveryCodes[i+1:]...)
+			return s.save(ctx, enrollment)
+		}
+	}
+	return ErrInvalidCode
+}
+
+// RegenerateRecoveryCodes remplace les codes de secours après vérification d'un code.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if err := s.countAttempt(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	s.resetAttempts(ctx, userID)
+	enrollment, err := s.enrollment(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enrollment.RecoveryCodes = hashes
+	return codes, s.save(ctx, enrollment)
+}
+
+// Disable désactive le MFA après vérification d'un code, sauf si le rôle l'impose.
+func (s *Service) Disable(ctx context.Context, userID, role, code string) error {
+	required, err := s.Required(ctx, role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrRequired
+	}
+	if err := s.countAttempt(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, enrollmentKey(userID), manageAttemptsKey(userID))
+}
+
+// Remaining retourne le nombre de codes de secours encore utilisables.
+func (s *Service) Remaining(ctx context.Context, userID string) (int, error) {
+	enrollment, err := s.enrollment(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return len(enrollment.RecoveryCodes), nil
+}
+
+// RequiredRoles retourne les rôles pour lesquels le MFA est obligatoire.
+func (s *Service) RequiredRoles(ctx context.Context) ([]string, error) {
+	var roles []string
+	err := store.GetJSON(ctx, s.store, requiredRolesKey, &roles)
+	if errors.Is(err, store.ErrNotFound) {
+		return s.requiredRoles, nil
+	}
+	return roles, err
+}
+
+// SetRequiredRoles modifie les rôles pour lesquels le MFA est obligatoire.
+func (s *Service) SetRequiredRoles(ctx context.Context, roles []string) error {
+	if roles == nil {
+		roles = []string{}
+	}
+	return store.SetJSON(ctx, s.store, requiredRolesKey, roles, 0)
+}
+
+// Required indique si le rôle impose le MFA.
+func (s *Service) Required(ctx context.Context, role string) (bool, error) {
+	roles, err := s.RequiredRoles(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		if r == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// StartChallenge crée un challenge de connexion et retourne son token opaque.
+func (s *Service) StartChallenge(ctx context.Context, subject tokens.Subject, enrollment bool) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	challenge := Challenge{
+		Subject:    subject,
+		Enrollment: enrollment,
+		ExpiresAt:  time.Now().Add(s.challengeTTL).UTC(),
+	}
+	if err := store.SetJSON(ctx, s.store, challengeKey(token), challenge, s.challengeTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Challenge retourne un challenge en cours sans le consommer.
+func (s *Service) Challenge(ctx context.Context, token string) (*Challenge, error) {
+	var challenge Challenge
+	if err := store.GetJSON(ctx, s.store, challengeKey(token), &challenge); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	attempts, err := s.store.Get(ctx, challengeAttemptsKey(token))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	challenge.Attempts, _ = strconv.Atoi(attempts)
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxChallengeAttempts {
+		return nil, ErrInvalidChallenge
+	}
+	return &challenge, nil
+}
+
+// CompleteChallenge vérifie le code du challenge. En cas de succès le challenge est
+// consommé ; sinon l'essai est comptabilisé. Pour un challenge d'enrôlement, le
+// premier code active le MFA et les codes de secours sont retournés.
+func (s *Service) CompleteChallenge(ctx context.Context, token, code string) (*Challenge, []string, error) {
+	challenge, err := s.Challenge(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// L'essai est compté avant la vérification : des essais parallèles ne peuvent
+	// pas dépasser la limite
+	attempts, err := s.store.IncrBy(ctx, challengeAttemptsKey(token), 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attempts == 1 {
+		s.store.Expire(ctx, challengeAttemptsKey(token), s.challengeTTL)
+	}
+	if attempts > maxChallengeAttempts {
+		return nil, nil, ErrInvalidChallenge
+	}
+	challenge.Attempts = int(attempts)
+
+	var recoveryCodes []string
+	if challenge.Enrollment {
+		recoveryCodes, err = s.confirm(ctx, challenge.Subject.UserID, code)
+	} else {
+		err = s.Verify(ctx, challenge.Subject.UserID, code)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.store.Delete(ctx, challengeKey(token), challengeAttemptsKey(token))
+	return challenge, recoveryCodes, nil
+}
+
+// claim réserve un marqueur d'usage unique ; un marqueur déjà présent signifie que
+// le code a été accepté par une autre requête.
+func (s *Service) claim(ctx context.Context, key string, ttl time.Duration) error {
+	first, err := s.store.SetNX(ctx, key, "1", ttl)
+	if err != nil {
+		return err
+	}
+	if !first {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// countAttempt compte un essai de code hors connexion, avant sa vérification, et le
+// refuse au-delà de maxManageAttempts.
+func (s *Service) countAttempt(ctx context.Context, userID string) error {
+	attempts, err := s.store.IncrBy(ctx, manageAttemptsKey(userID), 1)
+	if err != nil {
+		return err
+	}
+	if attempts == 1 {
+		s.store.Expire(ctx, manageAttemptsKey(userID), manageAttemptWindow)
+	}
+	if attempts > maxManageAttempts {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+func (s *Service) resetAttempts(ctx context.Context, userID string) {
+	s.store.Delete(ctx, manageAttemptsKey(userID))
+}
+
+func (s *Service) enrollment(ctx context.Context, userID string) (*Enrollment, error) {
+	var enrollment Enrollment
+	if err := store.GetJSON(ctx, s.store, enrollmentKey(userID), &enrollment); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotEnrolled
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+func (s *Service) save(ctx context.Context, enrollment *Enrollment) error {
+	return store.SetJSON(ctx, s.store, enrollmentKey(enrollment.UserID), enrollment, 0)
+}
+
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := hex.EncodeToString(b)
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,97 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChallengeAttemptsAreLimitedUnderConcurrency(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(store.NewMemory(), "mini-cloud", time.Minute, nil)
+	_, _, err := s.Begin(ctx, "123", "alice")
+	require.NoError(t, err)
+	token, err := s.StartChallenge(ctx, tokens.Subject{UserID: "123"}, true)
+	require.NoError(t, err)
+
+	// Essais parallèles : au plus maxChallengeAttempts codes sont vérifiés
+	var checked, rejected int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := s.CompleteChallenge(ctx, token, "not-a-code")
+			switch {
+			case errors.Is(err, ErrInvalidCode):
+				atomic.AddInt32(&checked, 1)
+			case errors.Is(err, ErrInvalidChallenge):
+				atomic.AddInt32(&rejected, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, int(checked), maxChallengeAttempts)
+	assert.Equal(t, int32(20), checked+rejected)
+	_, err = s.Challenge(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+}
+
+func TestVerifyAcceptsEachCodeOnceUnderConcurrency(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(store.NewMemory(), "mini-cloud", time.Minute, nil)
+	secret, _, err := s.Begin(ctx, "123", "alice")
+	require.NoError(t, err)
+	code, _ := Code(secret, time.Now())
+	recoveryCodes, err := s.Confirm(ctx, "123", code)
+	require.NoError(t, err)
+	next, _ := Code(secret, time.Now().Add(30*time.Second))
+
+	// Le même code TOTP ou de secours présenté en parallèle n'est accepté qu'une fois
+	for _, candidate := range []string{next, recoveryCodes[0]} {
+		var accepted int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if s.Verify(ctx, "123", candidate) == nil {
+					atomic.AddInt32(&accepted, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), accepted, candidate)
+	}
+}
+
+func TestManageAttemptsAreLimited(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(store.NewMemory(), "mini-cloud", time.Minute, nil)
+	secret, _, err := s.Begin(ctx, "123", "alice")
+	require.NoError(t, err)
+	code, _ := Code(secret, time.Now())
+	_, err = s.Confirm(ctx, "123", code)
+	require.NoError(t, err)
+
+	for i := 0; i < maxManageAttempts; i++ {
+		_, err := s.RegenerateRecoveryCodes(ctx, "123", "000000")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	// Limite atteinte : même un code valide est refusé
+	next, _ := Code(secret, time.Now().Add(30*time.Second))
+	assert.ErrorIs(t, s.Disable(ctx, "123", "user", next), ErrTooManyAttempts)
+	enabled, err := s.Enabled(ctx, "123")
+	require.NoError(t, err)
+	assert.True(t, enabled)
+}
@@ -0,0 +1,45 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeRFC6238Vectors(t *testing.T) {
+	// Vecteurs de test de la RFC 6238 (SHA1), tronqués à 6 chiffres
+	secret := base32NoPadding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, expected := range vectors {
+		code, err := Code(secret, time.Unix(ts, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code)
+	}
+}
+
+func TestValidateAcceptsClockSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Now()
+
+	previous, _ := Code(secret, now.Add(-30*time.Second))
+	_, ok := validate(secret, previous, now)
+	assert.True(t, ok)
+
+	old, _ := Code(secret, now.Add(-2*time.Minute))
+	_, ok = validate(secret, old, now)
+	assert.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("MiniCloud", "alice@example.com", "ABCDEF")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/MiniCloud:alice@example.com?"))
+	assert.Contains(t, uri, "secret=ABCDEF")
+}
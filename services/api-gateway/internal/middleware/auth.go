@@ -1,18 +1,164 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
+	"crypto/subtle"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/apikeys"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/audit"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/clientcert"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/sessions"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
 )
 
-func Auth(jwtSecret string) gin.HandlerFunc {
+// AuthOption configure le middleware Auth.
+type AuthOption func(*authOptions)
+
+type authOptions struct {
+	denylist *tokens.Denylist
+	keys     *tokens.KeySet
+	apiKeys  *apikeys.Repository
+	members  *tenants.Repository
+	tenant   bool
+	// Session navigateur : cookie du token d'accès et cookie CSRF
+	cookie     string
+	csrfCookie string
+	// Certificats clients (mTLS), acceptés seulement sous les préfixes de routes donnés
+	certs      *clientcert.Mapper
+	certRoutes []string
+	sessions   *sessions.Repository
+	// Émetteur, audience et tolérance d'horloge attendus des JWT
+	claims tokens.ClaimsConfig
+	// Impersonation : journal des requêtes et méthodes interdites
+	impersonationAudit   audit.Logger
+	impersonationBlocked []string
+}
+
+// WithImpersonation journalise chaque requête faite avec un token d'impersonation
+// (claim "act") et refuse celles dont la méthode HTTP est dans blocked (ex. DELETE).
+func WithImpersonation(logger audit.Logger, blocked []string) AuthOption {
+	return func(o *authOptions) {
+		o.impersonationAudit = logger
+		o.impersonationBlocked = blocked
+	}
+}
+
+// WithClaims vérifie l'émetteur (iss) et l'audience (aud) des JWT, avec une
+// tolérance d'horloge sur exp et nbf.
+func WithClaims(cfg tokens.ClaimsConfig) AuthOption {
+	return func(o *authOptions) {
+		o.claims = cfg
+	}
+}
+
+// WithSessions met à jour la dernière activité de la session (claim "sid") du token.
+func WithSessions(repo *sessions.Repository) AuthOption {
+	return func(o *authOptions) {
+		o.sessions = repo
+	}
+}
+
+// WithClientCerts accepte l'identité d'un certificat client vérifié (mTLS) à la place
+// d'un JWT, pour les routes dont le chemin commence par l'un des préfixes donnés.
+func WithClientCerts(mapper *clientcert.Mapper, routes []string) AuthOption {
+	return func(o *authOptions) {
+		o.certs = mapper
+		o.certRoutes = routes
+	}
+}
+
+// Header qui doit reprendre le cookie CSRF sur les requêtes authentifiées par cookie
+const CSRFHeader = "X-CSRF-Token"
+
+// WithSessionCookie accepte aussi le token d'accès dans un cookie (sessions navigateur).
+// Les requêtes qui modifient l'état doivent alors porter le header X-CSRF-Token,
+// identique au cookie csrfCookie et signé pour l'utilisateur (double submit).
+func WithSessionCookie(cookie, csrfCookie string) AuthOption {
+	return func(o *authOptions) {
+		o.cookie = cookie
+		o.csrfCookie = csrfCookie
+	}
+}
+
+// RequireTenant refuse les tokens et clés sans tenant_id. Le tenant est ajouté au
+// contexte ; un header X-Tenant-ID différent du tenant du token est refusé.
+func RequireTenant() AuthOption {
+	return func(o *authOptions) {
+		o.tenant = true
+	}
+}
+
+// WithAPIKeys accepte aussi les clés d'API (header X-API-Key ou Authorization: ApiKey).
+func WithAPIKeys(repo *apikeys.Repository) AuthOption {
+	return func(o *authOptions) {
+		o.apiKeys = repo
+	}
+}
+
+// WithMembership relit le rôle du membre dans son tenant à chaque utilisation d'une
+// clé d'API, plutôt que le rôle figé à sa création ; la clé d'un membre retiré est refusée.
+func WithMembership(repo *tenants.Repository) AuthOption {
+	return func(o *authOptions) {
+		o.members = repo
+	}
+}
+
+// WithKeySet vérifie les tokens avec un jeu de clés (RS256/EdDSA par kid) au lieu
+// du seul secret HS256.
+func WithKeySet(keys *tokens.KeySet) AuthOption {
+	return func(o *authOptions) {
+		o.keys = keys
+	}
+}
+
+// WithDenylist refuse les tokens révoqués (déconnexion, révocation par un admin).
+func WithDenylist(denylist *tokens.Denylist) AuthOption {
+	return func(o *authOptions) {
+		o.denylist = denylist
+	}
+}
+
+func Auth(jwtSecret string, opts ...AuthOption) gin.HandlerFunc {
+	options := &authOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.keys == nil {
+		options.keys = tokens.NewHMACKeySet(jwtSecret)
+	}
+
 	return func(c *gin.Context) {
-		// Récupérer le token depuis le header Authorization
+		// Les clients machine s'authentifient par clé d'API
+		if options.apiKeys != nil {
+			if apiKey := extractAPIKey(c); apiKey != "" {
+				authenticateAPIKey(c, options, apiKey)
+				return
+			}
+		}
+
+		// Services internes et objets connectés : certificat client, sans token
+		if c.GetHeader("Authorization") == "" {
+			if identity, ok := clientCertIdentity(c, options); ok {
+				authenticateClientCert(c, options, identity)
+				return
+			}
+		}
+
+		// Récupérer le token depuis le header Authorization, ou le cookie de session
 		authHeader := c.GetHeader("Authorization")
+		fromCookie := false
+		if authHeader == "" && options.cookie != "" {
+			if token, err := c.Cookie(options.cookie); err == nil && token != "" {
+				authHeader = "Bearer " + token
+				fromCookie = true
+			}
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 			c.Abort()
@@ -29,36 +175,261 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 
 		tokenString := tokenParts[1]
 
-		// Parser et valider le token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Vérifier la méthode de signature
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		})
-
+		// Parser et valider le token : signature (kid, alg), exp, nbf, iss et aud
+		claims, err := tokens.ParseClaims(tokenString, options.keys, options.claims)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		// Vérifier que le token est valide
-		if !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+		// Vérifier que le token n'a pas été révoqué
+		if options.denylist != nil {
+			var issuedAt time.Time
+			if claims.IssuedAt != nil {
+				issuedAt = claims.IssuedAt.Time
+			}
+			revoked, err := options.denylist.IsRevoked(c.Request.Context(), claims.ID, claims.UserID, issuedAt)
+			if err == nil && !revoked {
+				// Session fermée depuis un autre appareil : ses tokens ne sont plus valides
+				revoked, err = options.denylist.IsSessionRevoked(c.Request.Context(), claims.SessionID)
+			}
+			if err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token revocation"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token revoked"})
+				c.Abort()
+				return
+			}
+		}
+
+		// Un cookie est envoyé automatiquement par le navigateur : exiger le token CSRF
+		if fromCookie && !checkCSRF(c, options, jwtSecret, claims.UserID) {
+			return
+		}
+
+		// Vérifier le tenant du token
+		if !checkTenant(c, options, claims.TenantID) {
 			return
 		}
 
-		// Récupérer les claims
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			// Ajouter les informations utilisateur au contexte
-			c.Set("user_id", claims["user_id"])
-			c.Set("username", claims["username"])
-			c.Set("role", claims["role"])
+		// Ajouter les informations utilisateur au contexte
+		principal := &Principal{
+			UserID:    claims.UserID,
+			Username:  claims.Username,
+			Role:      claims.Role,
+			TenantID:  claims.TenantID,
+			SessionID: claims.SessionID,
+			Groups:    claims.Groups,
+			TokenID:   claims.ID,
+			Actor:     claims.Actor,
+		}
+		if fromCookie {
+			principal.AuthMethod = "cookie"
+		}
+		if claims.ExpiresAt != nil {
+			principal.ExpiresAt = claims.ExpiresAt.Time
+		}
+		setPrincipal(c, principal)
+		if claims.SessionID != "" && options.sessions != nil {
+			// Échec non bloquant : la dernière activité n'est qu'indicative
+			options.sessions.Touch(c.Request.Context(), claims.UserID, claims.SessionID, c.ClientIP())
+		}
+
+		// Impersonation : user_id est l'utilisateur cible, actor_id l'admin qui agit
+		actor := claims.Actor
+		if actor != nil && impersonationBlocked(options, c.Request.Method) {
+			forbidden(c, "not allowed while impersonating", nil)
+			auditImpersonation(c, options, "impersonation.blocked")
+			return
 		}
 
 		c.Next()
+
+		if actor != nil {
+			auditImpersonation(c, options, "impersonation.request")
+		}
+	}
+}
+
+func impersonationBlocked(options *authOptions, method string) bool {
+	for _, blocked := range options.impersonationBlocked {
+		if strings.EqualFold(blocked, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditImpersonation journalise une requête faite au nom d'un utilisateur.
+func auditImpersonation(c *gin.Context, options *authOptions, action string) {
+	if options.impersonationAudit == nil {
+		return
+	}
+	options.impersonationAudit.Log(c.Request.Context(), audit.Entry{
+		Action:   action,
+		Actor:    c.GetString("actor_username"),
+		Target:   c.GetString("user_id"),
+		TenantID: c.GetString("tenant_id"),
+		IP:       c.ClientIP(),
+		Data: map[string]interface{}{
+			"actor_id": c.GetString("actor_id"),
+			"method":   c.Request.Method,
+			"path":     c.Request.URL.Path,
+			"status":   c.Writer.Status(),
+		},
+	})
+}
+
+// extractAPIKey lit la clé depuis X-API-Key ou Authorization: ApiKey <clé>.
+func extractAPIKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(parts) == 2 && parts[0] == "ApiKey" {
+		return parts[1]
+	}
+	return ""
+}
+
+func authenticateAPIKey(c *gin.Context, options *authOptions, apiKey string) {
+	key, err := options.apiKeys.Authenticate(c.Request.Context(), apiKey)
+	switch {
+	case errors.Is(err, apikeys.ErrExpired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key expired"})
+		c.Abort()
+		return
+	case errors.Is(err, apikeys.ErrNotFound):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return
+	case err != nil:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify API key"})
+		c.Abort()
+		return
+	}
+
+	if options.members != nil && !applyMemberRole(c, options, key) {
+		return
+	}
+	if !checkTenant(c, options, key.TenantID) {
+		return
+	}
+
+	// Mêmes informations que pour un JWT, plus les scopes de la clé
+	setPrincipal(c, &Principal{
+		UserID:   key.UserID,
+		Username: key.Username,
+		Role:     key.Role,
+		TenantID: key.TenantID,
+		APIKeyID: key.ID,
+		Scopes:   key.Scopes,
+	})
+	c.Next()
+}
+
+// applyMemberRole applique ApplyMemberRole et répond 401 pour la clé d'un membre retiré.
+func applyMemberRole(c *gin.Context, options *authOptions, key *apikeys.Key) bool {
+	err := ApplyMemberRole(c.Request.Context(), options.members, key)
+	if errors.Is(err, tenants.ErrRemoved) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify API key"})
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// ApplyMemberRole remplace le rôle de la clé par le rôle actuel du membre et retire
+// le scope admin d'un membre qui n'est plus admin. Retourne tenants.ErrRemoved si le
+// propriétaire de la clé n'est plus membre du tenant.
+func ApplyMemberRole(ctx context.Context, members *tenants.Repository, key *apikeys.Key) error {
+	member, err := members.Get(ctx, key.TenantID, key.UserID)
+	if errors.Is(err, tenants.ErrNotFound) || (err == nil && member.RemovedAt != nil) {
+		return tenants.ErrRemoved
+	}
+	if err != nil {
+		return err
+	}
+
+	key.Role = member.Role
+	if member.Role != "admin" {
+		scopes := make([]string, 0, len(key.Scopes))
+		for _, scope := range key.Scopes {
+			if scope != "admin" {
+				scopes = append(scopes, scope)
+			}
+		}
+		key.Scopes = scopes
+	}
+	return nil
+}
+
+// clientCertIdentity retourne l'identité du certificat vérifié par la poignée de main TLS.
+func clientCertIdentity(c *gin.Context, options *authOptions) (*clientcert.Identity, bool) {
+	if options.certs == nil || c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+		return nil, false
+	}
+	allowed := false
+	for _, prefix := range options.certRoutes {
+		if strings.HasPrefix(c.Request.URL.Path, prefix) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, false
+	}
+	return options.certs.Identify(c.Request.TLS.VerifiedChains[0][0])
+}
+
+func authenticateClientCert(c *gin.Context, options *authOptions, identity *clientcert.Identity) {
+	if !checkTenant(c, options, identity.TenantID) {
+		return
+	}
+
+	setPrincipal(c, &Principal{
+		UserID:     identity.UserID,
+		Username:   identity.Username,
+		Role:       identity.Role,
+		TenantID:   identity.TenantID,
+		AuthMethod: "client_cert",
+	})
+	c.Next()
+}
+
+// checkTenant applique RequireTenant et vérifie le header X-Tenant-ID éventuel.
+func checkTenant(c *gin.Context, options *authOptions, tenantID string) bool {
+	if tenantID == "" && options.tenant {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has no tenant"})
+		c.Abort()
+		return false
+	}
+	if requested := c.GetHeader("X-Tenant-ID"); requested != "" && requested != tenantID {
+		forbidden(c, "tenant mismatch", gin.H{"tenant_id": requested})
+		return false
+	}
+	return true
+}
+
+// checkCSRF vérifie le token CSRF des requêtes qui modifient l'état.
+func checkCSRF(c *gin.Context, options *authOptions, secret, userID string) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	header := c.GetHeader(CSRFHeader)
+	cookie, _ := c.Cookie(options.csrfCookie)
+	if header == "" || subtle.ConstantTimeCompare([]byte(header), []byte(cookie)) != 1 || !tokens.VerifyCSRFToken(secret, userID, header) {
+		forbidden(c, "csrf token missing or invalid", nil)
+		return false
 	}
+	return true
 }
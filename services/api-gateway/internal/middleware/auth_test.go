@@ -0,0 +1,364 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/apikeys"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/clientcert"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/stretchr/testify/assert"
+)
+
+func signTestToken(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, _ := token.SignedString([]byte("test-secret"))
+	return tokenString
+}
+
+func requestWithToken(router *gin.Engine, tokenString string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthRevokedToken(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	denylist := tokens.NewDenylist(store.NewMemory(), time.Hour, time.Second)
+	router := gin.New()
+	router.Use(Auth("test-secret", WithDenylist(denylist)))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "success"})
+	})
+
+	exp := time.Now().Add(time.Hour)
+	tokenString := signTestToken(jwt.MapClaims{
+		"user_id": "123",
+		"jti":     "token-1",
+		"iat":     time.Now().Unix(),
+		"exp":     exp.Unix(),
+	})
+
+	// Avant révocation
+	w := requestWithToken(router, tokenString)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Après révocation du jti
+	denylist.RevokeToken(context.Background(), "token-1", exp)
+	w = requestWithToken(router, tokenString)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Token revoked")
+}
+
+func TestAuthRevokedUser(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	denylist := tokens.NewDenylist(store.NewMemory(), time.Hour, time.Second)
+	router := gin.New()
+	router.Use(Auth("test-secret", WithDenylist(denylist)))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "success"})
+	})
+
+	oldToken := signTestToken(jwt.MapClaims{
+		"user_id": "123",
+		"jti":     "old",
+		"iat":     time.Now().Add(-time.Minute).Unix(),
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	denylist.RevokeUser(context.Background(), "123")
+
+	// Les tokens émis avant la révocation sont refusés
+	w := requestWithToken(router, oldToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Un token émis après la révocation est accepté
+	newToken := signTestToken(jwt.MapClaims{
+		"user_id": "123",
+		"jti":     "new",
+		"iat":     time.Now().Add(2 * time.Second).Unix(),
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	w = requestWithToken(router, newToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthAPIKey(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	repo := apikeys.NewRepository(store.NewMemory())
+	router := gin.New()
+	router.Use(Auth("test-secret", WithAPIKeys(repo)))
+	router.GET("/test", RequireScope("files:read"), func(c *gin.Context) {
+		c.JSON(200, gin.H{"user_id": c.GetString("user_id"), "role": c.GetString("role")})
+	})
+	router.DELETE("/test", RequireScope("files:write"), func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "success"})
+	})
+
+	key := &apikeys.Key{UserID: "123", Username: "ci", Role: "user", Name: "ci", Scopes: []string{"files:read"}}
+	plaintext, err := repo.Create(context.Background(), key)
+	assert.NoError(t, err)
+
+	// X-API-Key et Authorization: ApiKey sont acceptés
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-API-Key", plaintext)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"user_id":"123"`)
+
+	req, _ = http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "ApiKey "+plaintext)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	stored, _ := repo.Get(context.Background(), "123", key.ID)
+	assert.NotNil(t, stored.LastUsedAt)
+
+	// Scope manquant
+	req, _ = http.NewRequest("DELETE", "/test", nil)
+	req.Header.Set("X-API-Key", plaintext)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Clé révoquée
+	assert.NoError(t, repo.Revoke(context.Background(), "123", key.ID))
+	req, _ = http.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-API-Key", plaintext)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthAPIKeyUsesMemberRole(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	st := store.NewMemory()
+	repo := apikeys.NewRepository(st)
+	members := tenants.NewRepository(st)
+	router := gin.New()
+	router.Use(Auth("test-secret", WithAPIKeys(repo), WithMembership(members)))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"role": c.GetString("role"), "scopes": c.GetStringSlice("scopes")})
+	})
+	request := func(plaintext string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-API-Key", plaintext)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	members.Join(ctx, "acme", "123", "root", "admin")
+	plaintext, err := repo.Create(ctx, &apikeys.Key{UserID: "123", Role: "admin", TenantID: "acme", Scopes: []string{"files:read", "admin"}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"role":"admin","scopes":["files:read","admin"]}`, request(plaintext).Body.String())
+
+	// Rétrogradé après la création de la clé : ni rôle ni scope admin
+	members.AssignRole(ctx, "acme", "123", "user")
+	assert.JSONEq(t, `{"role":"user","scopes":["files:read"]}`, request(plaintext).Body.String())
+
+	// Membre retiré : la clé est refusée
+	_, err = members.Remove(ctx, "acme", "123")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, request(plaintext).Code)
+}
+
+func TestAuthAPIKeyExpired(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	repo := apikeys.NewRepository(store.NewMemory())
+	router := gin.New()
+	router.Use(Auth("test-secret", WithAPIKeys(repo)))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "success"})
+	})
+
+	expiresAt := time.Now().Add(-time.Minute)
+	plaintext, _ := repo.Create(context.Background(), &apikeys.Key{UserID: "123", Scopes: []string{"files:read"}, ExpiresAt: &expiresAt})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-API-Key", plaintext)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "API key expired")
+}
+
+func TestAuthRequireTenant(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Auth("test-secret", RequireTenant()))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"tenant_id": c.GetString("tenant_id")})
+	})
+
+	// Token sans tenant
+	w := requestWithToken(router, signTestToken(jwt.MapClaims{
+		"user_id": "123",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	tokenString := signTestToken(jwt.MapClaims{
+		"user_id":   "123",
+		"tenant_id": "acme",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	})
+	w = requestWithToken(router, tokenString)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"tenant_id":"acme"`)
+
+	// Un header X-Tenant-ID différent du token est refusé
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	req.Header.Set("X-Tenant-ID", "other")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuthSessionCookieCSRF(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Auth("test-secret", WithSessionCookie("mc_access_token", "mc_csrf")))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"auth_method": c.GetString("auth_method")})
+	})
+	router.POST("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "success"})
+	})
+
+	tokenString := signTestToken(jwt.MapClaims{
+		"user_id": "123",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	csrf, _ := tokens.NewCSRFToken("test-secret", "123")
+	otherUserCSRF, _ := tokens.NewCSRFToken("test-secret", "456")
+
+	send := func(method, csrfCookie, csrfHeader string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/test", nil)
+		req.AddCookie(&http.Cookie{Name: "mc_access_token", Value: tokenString})
+		if csrfCookie != "" {
+			req.AddCookie(&http.Cookie{Name: "mc_csrf", Value: csrfCookie})
+		}
+		if csrfHeader != "" {
+			req.Header.Set(CSRFHeader, csrfHeader)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Lecture : pas de token CSRF requis
+	w := send("GET", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "cookie")
+
+	// Écriture sans token, avec un header différent du cookie, ou un token d'un autre utilisateur
+	assert.Equal(t, http.StatusForbidden, send("POST", "", "").Code)
+	assert.Equal(t, http.StatusForbidden, send("POST", csrf, otherUserCSRF).Code)
+	assert.Equal(t, http.StatusForbidden, send("POST", otherUserCSRF, otherUserCSRF).Code)
+
+	// Double submit valide
+	assert.Equal(t, http.StatusOK, send("POST", csrf, csrf).Code)
+
+	// Un token Bearer n'est pas concerné par le CSRF
+	req, _ := http.NewRequest("POST", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthClientCert(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mapper := clientcert.NewMapper([]clientcert.Identity{
+		{Match: "cn:sensor-42", UserID: "device-42", Username: "sensor-42", Role: "device", TenantID: "tenant-1"},
+	})
+	router := gin.New()
+	router.Use(Auth("test-secret", WithClientCerts(mapper, []string{"/devices"}), RequireTenant()))
+	handler := func(c *gin.Context) {
+		c.JSON(200, gin.H{"user_id": c.GetString("user_id"), "role": c.GetString("role")})
+	}
+	router.GET("/devices/telemetry", handler)
+	router.GET("/files", handler)
+
+	request := func(path string, verified bool) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "sensor-42"}}
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		if verified {
+			req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/devices/telemetry", true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "device-42")
+
+	// Certificat non vérifié, ou route non configurée : un JWT est exigé
+	assert.Equal(t, http.StatusUnauthorized, request("/devices/telemetry", false).Code)
+	assert.Equal(t, http.StatusUnauthorized, request("/files", true).Code)
+}
+
+func TestAuthPrincipal(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Auth("test-secret", WithClaims(tokens.ClaimsConfig{Issuer: "gateway", Audience: []string{"api"}})))
+	router.GET("/test", func(c *gin.Context) {
+		principal, ok := CurrentPrincipal(c)
+		if !ok {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.JSON(200, gin.H{"user_id": principal.UserID, "groups": principal.Groups, "actor": principal.Actor.UserID})
+	})
+
+	claims := jwt.MapClaims{
+		"user_id": "123",
+		"groups":  []string{"staff"},
+		"act":     map[string]interface{}{"sub": "admin-1", "username": "root"},
+		"iss":     "gateway",
+		"aud":     "api",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+	w := requestWithToken(router, signTestToken(claims))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":"123","groups":["staff"],"actor":"admin-1"}`, w.Body.String())
+
+	// Émetteur inattendu, audience absente, ou user_id numérique : token refusé
+	claims["iss"] = "other"
+	assert.Equal(t, http.StatusUnauthorized, requestWithToken(router, signTestToken(claims)).Code)
+	claims["iss"] = "gateway"
+	delete(claims, "aud")
+	assert.Equal(t, http.StatusUnauthorized, requestWithToken(router, signTestToken(claims)).Code)
+	claims["aud"] = "api"
+	claims["user_id"] = 123
+	assert.Equal(t, http.StatusUnauthorized, requestWithToken(router, signTestToken(claims)).Code)
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func requestAsRole(handler gin.HandlerFunc, role string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		c.Set("role", role)
+	}, handler, func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "success"})
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPermissionsAllows(t *testing.T) {
+	permissions := Permissions{
+		"admin":  {"*"},
+		"editor": {"files:*"},
+		"user":   {"files:read"},
+	}
+
+	assert.True(t, permissions.Allows("admin", "users:revoke"))
+	assert.True(t, permissions.Allows("editor", "files:delete"))
+	assert.False(t, permissions.Allows("editor", "users:revoke"))
+	assert.True(t, permissions.Allows("user", "files:read"))
+	assert.False(t, permissions.Allows("user", "files:write"))
+	assert.False(t, permissions.Allows("", "files:read"))
+}
+
+func TestRequireRole(t *testing.T) {
+	w := requestAsRole(RequireRole("admin"), "admin")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = requestAsRole(RequireRole("admin"), "user")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var body map[string]string
+	json.Unmarshal(w.Body.Bytes(), &body)
+	assert.Equal(t, "Forbidden", body["error"])
+	assert.Equal(t, "missing role", body["reason"])
+	assert.Equal(t, "admin", body["required"])
+}
+
+func TestRequirePermission(t *testing.T) {
+	permissions := Permissions{"user": {"files:read"}}
+
+	w := requestAsRole(RequirePermission(permissions, "files:read"), "user")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = requestAsRole(RequirePermission(permissions, "files:delete"), "user")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"required":"files:delete"`)
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Taille minimale (si connue) en dessous de laquelle on ne compresse pas
+const minCompressSize = 1024
+
+// Types de contenu qui valent la peine d'être compressés. Tout le reste
+// (images, vidéos, archives...) est considéré comme déjà compressé.
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/x-ndjson",
+	"application/xml",
+	"application/javascript",
+	"application/csv",
+	"image/svg+xml",
+}
+
+// Compress compresse la réponse en zstd ou gzip selon l'en-tête Accept-Encoding.
+// Les requêtes Range et les réponses partielles ne sont jamais compressées.
+func Compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept-Encoding")
+
+		// Les octets d'un Range se réfèrent à la représentation non compressée
+		if c.GetHeader("Range") != "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+		c.Writer = cw
+		defer cw.Close()
+
+		c.Next()
+	}
+}
+
+// Decompress accepte les corps de requête envoyés avec Content-Encoding: gzip
+// et les expose décompressés aux handlers, dans la limite de maxBytes.
+func Decompress(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		switch encoding {
+		case "", "identity":
+			c.Next()
+			return
+		case "gzip", "x-gzip":
+		default:
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported content encoding"})
+			c.Abort()
+			return
+		}
+
+		gz, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gzip body"})
+			c.Abort()
+			return
+		}
+		defer gz.Close()
+
+		// Le corps est désormais décompressé, sa taille n'est plus connue
+		var body io.Reader = gz
+		if maxBytes > 0 {
+			body = http.MaxBytesReader(c.Writer, io.NopCloser(gz), maxBytes)
+		}
+		c.Request.Body = io.NopCloser(body)
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+
+		c.Next()
+	}
+}
+
+// negotiateEncoding choisit l'encodage à utiliser, zstd en priorité puis gzip.
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qualities[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range []string{"zstd", "gzip"} {
+		q, ok := qualities[enc]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// isCompressible indique si le type de contenu mérite d'être compressé.
+func isCompressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.TrimSpace(contentType)
+	if strings.HasSuffix(contentType, "+json") || strings.HasSuffix(contentType, "+xml") {
+		return true
+	}
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter décide à la première écriture s'il faut compresser,
+// une fois que le handler a fixé le statut et les en-têtes.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	decided  bool
+	encoder  io.WriteCloser
+}
+
+func (w *compressWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	h := w.Header()
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent ||
+		status == http.StatusPartialContent || status == http.StatusNotModified {
+		return
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return
+	}
+	if !isCompressible(h.Get("Content-Type")) {
+		return
+	}
+	if cl := h.Get("Content-Length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < minCompressSize {
+			return
+		}
+	}
+
+	switch w.encoding {
+	case "zstd":
+		enc, err := zstd.NewWriter(w.ResponseWriter, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return
+		}
+		w.encoder = enc
+	case "gzip":
+		w.encoder = gzip.NewWriter(w.ResponseWriter)
+	default:
+		return
+	}
+
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	h.Del("Accept-Ranges")
+	// L'ETag d'une représentation compressée ne peut plus être fort
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", "W/"+etag)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+	return w.encoder.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Flush() {
+	// Un Flush avant la première écriture envoie les en-têtes : décider maintenant
+	w.decide()
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Close termine le flux compressé s'il a été ouvert.
+func (w *compressWriter) Close() error {
+	if w.encoder == nil {
+		return nil
+	}
+	return w.encoder.Close()
+}
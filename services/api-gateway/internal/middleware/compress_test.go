@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCompressRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	csv := strings.Repeat("id,name,size\n1,report,42\n", 200)
+
+	router.GET("/export", Compress(), func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/csv", []byte(csv))
+	})
+	router.GET("/archive", Compress(), func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/zip", []byte(csv))
+	})
+	router.GET("/content", Compress(), func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		http.ServeContent(c.Writer, c.Request, "export.csv", time.Now(), strings.NewReader(csv))
+	})
+	return router
+}
+
+func TestCompressGzip(t *testing.T) {
+	router := setupCompressRouter()
+
+	req, _ := http.NewRequest("GET", "/export", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	gz, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(gz)
+	assert.Contains(t, string(body), "id,name,size")
+}
+
+func TestCompressFlushBeforeWrite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/stream", Compress(), func(c *gin.Context) {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Writer.Flush()
+		c.Writer.WriteString(strings.Repeat(`{"event":"upload"}`+"\n", 100))
+	})
+
+	req, _ := http.NewRequest("GET", "/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Les en-têtes envoyés au Flush annoncent déjà l'encodage du corps
+	assert.Equal(t, "gzip", w.Result().Header.Get("Content-Encoding"))
+	gz, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(gz)
+	assert.Contains(t, string(body), `{"event":"upload"}`)
+}
+
+func TestCompressPrefersZstd(t *testing.T) {
+	router := setupCompressRouter()
+
+	req, _ := http.NewRequest("GET", "/export", nil)
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "zstd", w.Header().Get("Content-Encoding"))
+
+	dec, err := zstd.NewReader(w.Body)
+	assert.NoError(t, err)
+	defer dec.Close()
+	body, _ := io.ReadAll(dec)
+	assert.Contains(t, string(body), "1,report,42")
+}
+
+func TestCompressSkipsCompressedTypes(t *testing.T) {
+	router := setupCompressRouter()
+
+	req, _ := http.NewRequest("GET", "/archive", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
+
+func TestCompressKeepsRangeRequests(t *testing.T) {
+	router := setupCompressRouter()
+
+	req, _ := http.NewRequest("GET", "/content", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-11")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "id,name,size", w.Body.String())
+}
+
+func TestDecompressGzipUpload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/upload", Decompress(1<<20), func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	})
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("hello mini-cloud"))
+	gz.Close()
+
+	req, _ := http.NewRequest("POST", "/upload", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello mini-cloud", w.Body.String())
+
+	// Encodage non supporté
+	req, _ = http.NewRequest("POST", "/upload", strings.NewReader("data"))
+	req.Header.Set("Content-Encoding", "br")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
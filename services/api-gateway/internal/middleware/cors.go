@@ -1,14 +1,24 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"slices"
 
-func Cors() gin.HandlerFunc {
+	"github.com/gin-gonic/gin"
+)
+
+// Cors autorise les requêtes cross-origin, avec cookies, des seules origines
+// listées : l'origine est renvoyée telle quelle, "*" étant refusé par les
+// navigateurs avec Access-Control-Allow-Credentials.
+func Cors(allowedOrigins []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Headers CORS
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
-		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Vary", "Origin")
+		if origin := c.GetHeader("Origin"); origin != "" && slices.Contains(allowedOrigins, origin) {
+			// Headers CORS
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Encoding, Accept, Authorization, X-API-Key, X-Tenant-ID, X-CSRF-Token, X-Request-ID, Range")
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
 
 		// Gérer les requêtes OPTIONS (preflight)
 		if c.Request.Method == "OPTIONS" {
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/pkg/identity"
+)
+
+// Identifiant de requête accepté depuis le client (ou un proxy en amont)
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID attribue un identifiant à chaque requête : celui du header X-Request-ID
+// s'il est bien formé, sinon un nouveau. Il est renvoyé dans la réponse et transmis
+// aux services internes avec l'identité (voir PropagateIdentity).
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(identity.HeaderRequestID)
+		if !requestIDPattern.MatchString(requestID) {
+			b := make([]byte, 16)
+			rand.Read(b)
+			requestID = hex.EncodeToString(b)
+		}
+
+		c.Set("request_id", requestID)
+		c.Header(identity.HeaderRequestID, requestID)
+		setIdentity(c, identity.Identity{RequestID: requestID})
+		c.Next()
+	}
+}
+
+// PropagateIdentity ajoute l'utilisateur authentifié à l'identité transmise aux
+// services internes. À placer après Auth ; les requêtes sortantes sont signées par
+// un client HTTP dont le Transport est identity.Transport.
+func PropagateIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setIdentity(c, identity.Identity{
+			UserID:    c.GetString("user_id"),
+			Role:      c.GetString("role"),
+			TenantID:  c.GetString("tenant_id"),
+			RequestID: c.GetString("request_id"),
+			ActorID:   c.GetString("actor_id"),
+		})
+		c.Next()
+	}
+}
+
+func setIdentity(c *gin.Context, id identity.Identity) {
+	c.Request = c.Request.WithContext(identity.NewContext(c.Request.Context(), id))
+}
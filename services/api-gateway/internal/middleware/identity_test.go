@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mtk14m/mini-cloud/api-gateway/pkg/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/test", func(c *gin.Context) {
+		id, _ := identity.FromContext(c.Request.Context())
+		c.String(http.StatusOK, id.RequestID)
+	})
+
+	// Identifiant fourni par le client
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "trace-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "trace-42", w.Body.String())
+	assert.Equal(t, "trace-42", w.Header().Get("X-Request-ID"))
+
+	// Identifiant mal formé : remplacé
+	req, _ = http.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "bad id\r\n")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Len(t, w.Body.String(), 32)
+	assert.Equal(t, w.Body.String(), w.Header().Get("X-Request-ID"))
+}
+
+func TestPropagateIdentity(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), Auth("test-secret"), PropagateIdentity())
+	router.GET("/test", func(c *gin.Context) {
+		id, _ := identity.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, id)
+	})
+
+	token := signTestToken(jwt.MapClaims{"user_id": "123", "role": "user", "tenant_id": "acme"})
+	w := requestWithToken(router, token)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var id identity.Identity
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &id))
+	assert.Equal(t, "123", id.UserID)
+	assert.Equal(t, "user", id.Role)
+	assert.Equal(t, "acme", id.TenantID)
+	assert.Equal(t, w.Header().Get("X-Request-ID"), id.RequestID)
+	assert.Empty(t, id.ActorID)
+
+	// Impersonation : l'admin est transmis aux services
+	token = signTestToken(jwt.MapClaims{"user_id": "123", "role": "user", "act": map[string]string{"sub": "admin-1"}})
+	w = requestWithToken(router, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &id))
+	assert.Equal(t, "admin-1", id.ActorID)
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/policy"
+)
+
+// ErrUnknownOwner est retourné quand le propriétaire de la ressource n'existe pas :
+// la requête est refusée plutôt qu'évaluée avec un owner_id vide.
+var ErrUnknownOwner = errors.New("resource owner is unknown")
+
+// ResourceFunc décrit la ressource visée par une requête. needs indique si la policy
+// examine un attribut (ex. "resource.owner_id"), pour ne résoudre que ce qui sert.
+type ResourceFunc func(c *gin.Context, needs func(attribute string) bool) (policy.Resource, error)
+
+// OwnerFunc retourne le propriétaire de la ressource id, ou ErrUnknownOwner.
+type OwnerFunc func(ctx context.Context, id string) (string, error)
+
+// ParamResource construit la ressource à partir d'un paramètre de route.
+func ParamResource(resourceType, param string) ResourceFunc {
+	return func(c *gin.Context, _ func(string) bool) (policy.Resource, error) {
+		return policy.Resource{Type: resourceType, ID: c.Param(param)}, nil
+	}
+}
+
+// OwnedResource construit la ressource comme ParamResource et renseigne son
+// propriétaire, seulement si une règle de l'action porte sur resource.owner_id.
+func OwnedResource(resourceType, param string, owner OwnerFunc) ResourceFunc {
+	return func(c *gin.Context, needs func(string) bool) (policy.Resource, error) {
+		resource := policy.Resource{Type: resourceType, ID: c.Param(param)}
+		if !needs("resource.owner_id") {
+			return resource, nil
+		}
+		ownerID, err := owner(c.Request.Context(), resource.ID)
+		if err != nil {
+			return resource, err
+		}
+		resource.OwnerID = ownerID
+		return resource, nil
+	}
+}
+
+// Policy évalue les règles déclaratives pour l'action. À placer après Auth,
+// dont il reprend l'utilisateur, le rôle et les groupes.
+func Policy(engine *policy.Engine, action string, resource ResourceFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		in := PolicyInput(c, action)
+		if resource != nil {
+			var err error
+			in.Resource, err = resource(c, func(attribute string) bool {
+				return engine.References(action, attribute)
+			})
+			if errors.Is(err, ErrUnknownOwner) {
+				forbidden(c, "resource owner is unknown", nil)
+				return
+			}
+			if err != nil {
+				log.Printf("policy: failed to resolve resource: %v", err)
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to resolve resource"})
+				return
+			}
+		}
+
+		decision := engine.Evaluate(in)
+		if !decision.Allowed {
+			forbidden(c, decision.Reason, gin.H{"rule": decision.Rule})
+			return
+		}
+		c.Next()
+	}
+}
+
+// PolicyInput construit l'entrée d'évaluation à partir du contexte de la requête.
+func PolicyInput(c *gin.Context, action string) policy.Input {
+	var groups []string
+	value, _ := c.Get("groups")
+	switch g := value.(type) {
+	case []string:
+		groups = g
+	case []interface{}:
+		for _, v := range g {
+			if s, ok := v.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	return policy.Input{
+		Subject: policy.Subject{
+			UserID:   c.GetString("user_id"),
+			Username: c.GetString("username"),
+			Role:     c.GetString("role"),
+			TenantID: c.GetString("tenant_id"),
+			Groups:   groups,
+		},
+		Action: action,
+		Request: map[string]interface{}{
+			"method":         c.Request.Method,
+			"path":           c.FullPath(),
+			"content_length": c.Request.ContentLength,
+			"ip":             c.ClientIP(),
+			"api_key":        c.GetString("api_key_id") != "",
+		},
+	}
+}
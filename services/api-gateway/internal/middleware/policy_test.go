@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+const ownerPolicy = `
+default: deny
+rules:
+  - id: own-files
+    effect: allow
+    actions: ["files:read"]
+    when:
+      - resource.owner_id == subject.user_id
+`
+
+func TestPolicyResolvesOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(ownerPolicy), 0600))
+	engine, err := policy.Load(path)
+	assert.NoError(t, err)
+
+	owners := func(ctx context.Context, id string) (string, error) {
+		switch id {
+		case "f1":
+			return "123", nil
+		case "f2":
+			return "456", nil
+		case "down":
+			return "", errors.New("connection refused")
+		}
+		return "", ErrUnknownOwner
+	}
+	router := gin.New()
+	router.GET("/files/:id", func(c *gin.Context) {
+		c.Set("user_id", "123")
+	}, Policy(engine, "files:read", OwnedResource("file", "id", owners)), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for id, code := range map[string]int{
+		"f1":      http.StatusOK,
+		"f2":      http.StatusForbidden,
+		"missing": http.StatusForbidden,
+		"down":    http.StatusServiceUnavailable,
+	} {
+		req, _ := http.NewRequest("GET", "/files/"+id, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, code, w.Code, id)
+	}
+}
+
+func TestPolicySkipsOwnerLookup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	called := false
+	owners := func(ctx context.Context, id string) (string, error) {
+		called = true
+		return "", errors.New("file service unavailable")
+	}
+
+	// Sans policy chargée, ou sans règle sur resource.owner_id, le service de
+	// fichiers n'est pas appelé
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(ownerPolicy), 0600))
+	loaded, err := policy.Load(path)
+	assert.NoError(t, err)
+	engines := map[string]*policy.Engine{"files:read": policy.NewEngine(), "files:delete": loaded}
+	for action, engine := range engines {
+		router := gin.New()
+		router.DELETE("/files/:id", Policy(engine, action, OwnedResource("file", "id", owners)), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		req, _ := http.NewRequest("DELETE", "/files/f1", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.False(t, called, action)
+	}
+}
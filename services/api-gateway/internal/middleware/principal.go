@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+)
+
+const principalKey = "principal"
+
+// Principal est l'identité authentifiée par Auth, quelle que soit la méthode
+// (JWT, clé d'API, certificat client).
+type Principal struct {
+	UserID    string
+	Username  string
+	Role      string
+	TenantID  string
+	SessionID string
+	Groups    []string
+	// TokenID est le jti du token d'accès, utilisé pour la révocation
+	TokenID string
+	// AuthMethod vaut "cookie" ou "client_cert" ; vide pour un Bearer ou une clé d'API
+	AuthMethod string
+	APIKeyID   string
+	Scopes     []string
+	// Actor est l'admin qui agit au nom de l'utilisateur (impersonation)
+	Actor     *tokens.Actor
+	ExpiresAt time.Time
+}
+
+// CurrentPrincipal retourne l'identité authentifiée de la requête ; false hors des
+// routes protégées par Auth.
+func CurrentPrincipal(c *gin.Context) (*Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	p, ok := value.(*Principal)
+	return p, ok
+}
+
+// setPrincipal ajoute l'identité au contexte, ainsi que les clés individuelles
+// (user_id, role...) lues par les handlers existants.
+func setPrincipal(c *gin.Context, p *Principal) {
+	c.Set(principalKey, p)
+	c.Set("user_id", p.UserID)
+	c.Set("username", p.Username)
+	c.Set("role", p.Role)
+	c.Set("tenant_id", p.TenantID)
+	if p.TokenID != "" {
+		c.Set("jti", p.TokenID)
+	}
+	if p.SessionID != "" {
+		c.Set("session_id", p.SessionID)
+	}
+	if p.Groups != nil {
+		c.Set("groups", p.Groups)
+	}
+	if p.AuthMethod != "" {
+		c.Set("auth_method", p.AuthMethod)
+	}
+	if p.APIKeyID != "" {
+		c.Set("api_key_id", p.APIKeyID)
+		c.Set("scopes", p.Scopes)
+	}
+	if p.Actor != nil {
+		c.Set("actor_id", p.Actor.UserID)
+		c.Set("actor_username", p.Actor.Username)
+	}
+	if !p.ExpiresAt.IsZero() {
+		c.Set("token_expires_at", p.ExpiresAt)
+	}
+}
@@ -29,41 +29,50 @@ func NewRateLimiter(redisURL string, limit int) *RateLimiter {
 
 func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Récupérer l'IP du client
-		clientIP := c.ClientIP()
+		// Clé Redis pour l'IP du client
+		key := fmt.Sprintf("rate_limit:%s", c.ClientIP())
+		rl.check(c, key, rl.limit)
+	}
+}
 
-		// Clé Redis pour cette IP
-		key := fmt.Sprintf("rate_limit:%s", clientIP)
+// PerTenant limite le nombre total de requêtes d'un tenant, tous utilisateurs
+// confondus. À placer après Auth, qui fournit le tenant_id.
+func (rl *RateLimiter) PerTenant(limit int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := fmt.Sprintf("rate_limit:tenant:%s", c.GetString("tenant_id"))
+		rl.check(c, key, limit)
+	}
+}
 
-		// Incrémenter le compteur
-		count, err := rl.redis.Incr(c.Request.Context(), key).Result()
-		if err != nil {
-			// Si Redis n'est pas disponible, continuer sans rate limiting
-			c.Next()
-			return
-		}
+func (rl *RateLimiter) check(c *gin.Context, key string, limit int) {
+	// Incrémenter le compteur
+	count, err := rl.redis.Incr(c.Request.Context(), key).Result()
+	if err != nil {
+		// Si Redis n'est pas disponible, continuer sans rate limiting
+		c.Next()
+		return
+	}
 
-		// Si c'est la première requête, définir l'expiration
-		if count == 1 {
-			rl.redis.Expire(c.Request.Context(), key, rl.window)
-		}
+	// Si c'est la première requête, définir l'expiration
+	if count == 1 {
+		rl.redis.Expire(c.Request.Context(), key, rl.window)
+	}
 
-		// Vérifier la limite
-		if count > int64(rl.limit) {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":  "Rate limit exceeded",
-				"limit":  rl.limit,
-				"window": rl.window.String(),
-			})
-			c.Abort()
-			return
-		}
+	// Vérifier la limite
+	if count > int64(limit) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":  "Rate limit exceeded",
+			"limit":  limit,
+			"window": rl.window.String(),
+		})
+		c.Abort()
+		return
+	}
 
-		// Ajouter les headers de rate limiting
-		c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", rl.limit))
-		c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", rl.limit-int(count)))
-		c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", time.Now().Add(rl.window).Unix()))
+	// Ajouter les headers de rate limiting
+	c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
+	c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", limit-int(count)))
+	c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", time.Now().Add(rl.window).Unix()))
 
-		c.Next()
-	}
+	c.Next()
 }
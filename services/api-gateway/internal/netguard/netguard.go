@@ -0,0 +1,147 @@
+// Package netguard empêche les requêtes sortantes vers des adresses internes
+// (SSRF) : réseaux privés, loopback, link-local, métadonnées cloud, etc.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress est retourné quand l'URL pointe vers un réseau interdit.
+var ErrBlockedAddress = errors.New("destination address is not allowed")
+
+// Plages refusées par défaut : réseaux privés, loopback, link-local, etc.
+var blockedPrefixes = mustParsePrefixes(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+// Guard décide si une adresse IP peut être contactée.
+type Guard struct {
+	allowed []netip.Prefix
+}
+
+// New construit le garde à partir d'une liste blanche de CIDR ou d'IP.
+func New(allowlist []string) (*Guard, error) {
+	g := &Guard{}
+	for _, entry := range allowlist {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowlist entry %q: %v", entry, err)
+			}
+			g.allowed = append(g.allowed, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist entry %q: %v", entry, err)
+		}
+		g.allowed = append(g.allowed, prefix.Masked())
+	}
+	return g, nil
+}
+
+// Allows indique si l'adresse est publique ou dans la liste blanche.
+func (g *Guard) Allows(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range g.allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Control est branché sur le net.Dialer : il vérifie l'adresse réellement
+// contactée, après résolution DNS, ce qui protège aussi des redirections
+// et du DNS rebinding.
+func (g *Guard) Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !g.Allows(addr) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+	}
+	return nil
+}
+
+// CheckHost résout host et refuse qu'une de ses adresses soit interdite. Utile pour
+// rejeter une URL dès sa saisie ; la vérification au moment de la connexion reste
+// nécessaire, la résolution DNS pouvant changer.
+func (g *Guard) CheckHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !g.Allows(addr) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !g.Allows(addr) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+		}
+	}
+	return nil
+}
+
+// Transport retourne un transport HTTP dont les connexions passent par le garde.
+func (g *Guard) Transport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: g.Control,
+	}
+	return &http.Transport{
+		// Pas de proxy : il contournerait la vérification des adresses
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	}
+}
+
+func mustParsePrefixes(values ...string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, len(values))
+	for i, v := range values {
+		prefixes[i] = netip.MustParsePrefix(v)
+	}
+	return prefixes
+}
@@ -0,0 +1,33 @@
+package netguard
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuard(t *testing.T) {
+	guard, err := New([]string{"10.1.0.0/16"})
+	assert.NoError(t, err)
+
+	assert.False(t, guard.Allows(netip.MustParseAddr("169.254.169.254")))
+	assert.False(t, guard.Allows(netip.MustParseAddr("192.168.1.1")))
+	assert.False(t, guard.Allows(netip.MustParseAddr("::ffff:127.0.0.1")))
+	assert.True(t, guard.Allows(netip.MustParseAddr("10.1.2.3")))
+	assert.True(t, guard.Allows(netip.MustParseAddr("93.184.216.34")))
+
+	_, err = New([]string{"not-an-ip"})
+	assert.Error(t, err)
+}
+
+func TestCheckHost(t *testing.T) {
+	guard, _ := New(nil)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, guard.CheckHost(ctx, "127.0.0.1"), ErrBlockedAddress)
+	assert.ErrorIs(t, guard.CheckHost(ctx, "169.254.169.254"), ErrBlockedAddress)
+	assert.ErrorIs(t, guard.CheckHost(ctx, "localhost"), ErrBlockedAddress)
+	assert.NoError(t, guard.CheckHost(ctx, "93.184.216.34"))
+}
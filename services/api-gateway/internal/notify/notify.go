@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// Nombre de notifications conservées par utilisateur
+const inboxSize = 100
+
+// Event décrit un évènement destiné à un utilisateur.
+type Event struct {
+	Type      string                 `json:"type"`
+	UserID    string                 `json:"user_id"`
+	TenantID  string                 `json:"tenant_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Notifier transmet les évènements aux utilisateurs concernés.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Inbox conserve les dernières notifications de chaque utilisateur dans le store.
+type Inbox struct {
+	store store.Store
+}
+
+// NewInbox crée une boîte de notifications adossée au store.
+func NewInbox(st store.Store) *Inbox {
+	return &Inbox{store: st}
+}
+
+func inboxKey(userID string) string {
+	return fmt.Sprintf("notifications:%s", userID)
+}
+
+// Notify ajoute l'évènement à la boîte de son destinataire.
+func (i *Inbox) Notify(ctx context.Context, event Event) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return i.store.LPush(ctx, inboxKey(event.UserID), string(data), inboxSize)
+}
+
+// List retourne les notifications de l'utilisateur, les plus récentes d'abord.
+func (i *Inbox) List(ctx context.Context, userID string) ([]Event, error) {
+	items, err := i.store.LRange(ctx, inboxKey(userID), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(items))
+	for _, item := range items {
+		var event Event
+		if err := json.Unmarshal([]byte(item), &event); err == nil {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// multiNotifier diffuse chaque évènement à plusieurs notifiers.
+type multiNotifier []Notifier
+
+// Multi combine plusieurs notifiers. Les erreurs n'interrompent pas la diffusion.
+func Multi(notifiers ...Notifier) Notifier {
+	return multiNotifier(notifiers)
+}
+
+func (m multiNotifier) Notify(ctx context.Context, event Event) error {
+	var firstErr error
+	for _, n := range m {
+		if err := n.Notify(ctx, event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,130 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// StateTTL est la durée de validité d'une connexion en cours
+const StateTTL = 10 * time.Minute
+
+var (
+	// ErrUnknownProvider est retourné pour un fournisseur non configuré.
+	ErrUnknownProvider = errors.New("unknown oidc provider")
+	// ErrInvalidState est retourné pour un state inconnu, expiré ou déjà utilisé.
+	ErrInvalidState = errors.New("invalid or expired oidc state")
+)
+
+// Pending est l'état conservé entre la redirection et le callback.
+type Pending struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// Registry regroupe les fournisseurs configurés et les connexions en cours.
+type Registry struct {
+	providers map[string]*Provider
+	store     store.Store
+}
+
+// NewRegistry crée les fournisseurs décrits dans la configuration.
+func NewRegistry(providers []config.OIDCProvider, st store.Store, client *http.Client) *Registry {
+	r := &Registry{providers: make(map[string]*Provider), store: st}
+	for _, p := range providers {
+		r.providers[p.Name] = NewProvider(p, client)
+	}
+	return r
+}
+
+// Provider retourne un fournisseur par son nom.
+func (r *Registry) Provider(name string) (*Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+func stateKey(state string) string {
+	return fmt.Sprintf("oidc_state:%s", state)
+}
+
+// Begin démarre une connexion : génère state, nonce et verifier PKCE et retourne
+// l'URL vers laquelle rediriger le navigateur, ainsi que le state, à lier au navigateur
+// (cookie) pour empêcher qu'un tiers ne termine la connexion à sa place.
+func (r *Registry) Begin(ctx context.Context, providerName string) (string, string, error) {
+	provider, err := r.Provider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomString(48)
+	if err != nil {
+		return "", "", err
+	}
+
+	pending := Pending{Provider: providerName, Nonce: nonce, CodeVerifier: verifier}
+	if err := store.SetJSON(ctx, r.store, stateKey(state), pending, StateTTL); err != nil {
+		return "", "", err
+	}
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, CodeChallenge(verifier))
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// Consume récupère et invalide l'état associé à state (usage unique).
+func (r *Registry) Consume(ctx context.Context, providerName, state string) (*Pending, error) {
+	if state == "" {
+		return nil, ErrInvalidState
+	}
+	var pending Pending
+	if err := store.GetJSON(ctx, r.store, stateKey(state), &pending); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrInvalidState
+		}
+		return nil, err
+	}
+	first, err := r.store.SetNX(ctx, stateKey(state)+":used", "1", StateTTL)
+	if err != nil {
+		return nil, err
+	}
+	r.store.Delete(ctx, stateKey(state))
+	if !first || pending.Provider != providerName {
+		return nil, ErrInvalidState
+	}
+	return &pending, nil
+}
+
+// CodeChallenge calcule le code_challenge PKCE S256 d'un verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,204 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// condition est une comparaison "<gauche> <opérateur> <droite>", par exemple
+// `resource.owner_id != subject.user_id`, `request.content_length > 10485760`
+// ou `'ops' in subject.groups`. Chaque opérande est un attribut (chemin pointé)
+// ou un littéral (chaîne entre quotes, nombre, true/false).
+type condition struct {
+	raw   string
+	left  operand
+	op    string
+	right operand
+}
+
+type operand struct {
+	attr    string
+	literal interface{}
+}
+
+var operators = map[string]bool{
+	"==": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true, "in": true, "not in": true,
+}
+
+func parseCondition(raw string) (condition, error) {
+	tokens, err := tokenize(raw)
+	if err != nil {
+		return condition{}, err
+	}
+	if len(tokens) == 4 && tokens[1] == "not" && tokens[2] == "in" {
+		tokens = []string{tokens[0], "not in", tokens[3]}
+	}
+	if len(tokens) != 3 || !operators[tokens[1]] {
+		return condition{}, fmt.Errorf("invalid condition %q: expected <left> <op> <right>", raw)
+	}
+
+	left, err := parseOperand(tokens[0])
+	if err != nil {
+		return condition{}, fmt.Errorf("invalid condition %q: %v", raw, err)
+	}
+	right, err := parseOperand(tokens[2])
+	if err != nil {
+		return condition{}, fmt.Errorf("invalid condition %q: %v", raw, err)
+	}
+	return condition{raw: raw, left: left, op: tokens[1], right: right}, nil
+}
+
+func tokenize(raw string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	var quote rune
+	for _, r := range raw {
+		switch {
+		case quote != 0:
+			current.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			current.WriteRune(r)
+		case r == ' ' || r == '\t':
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated string in %q", raw)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+func parseOperand(token string) (operand, error) {
+	if len(token) >= 2 && (token[0] == '\'' || token[0] == '"') && token[len(token)-1] == token[0] {
+		return operand{literal: token[1 : len(token)-1]}, nil
+	}
+	if token == "true" || token == "false" {
+		return operand{literal: token == "true"}, nil
+	}
+	if n, err := strconv.ParseFloat(token, 64); err == nil {
+		return operand{literal: n}, nil
+	}
+	for _, part := range strings.Split(token, ".") {
+		if part == "" {
+			return operand{}, fmt.Errorf("invalid attribute %q", token)
+		}
+	}
+	return operand{attr: token}, nil
+}
+
+// eval évalue la condition. Un attribut absent rend la condition fausse : une règle
+// ne s'applique jamais sur une information que le gateway ne connaît pas.
+func (cond condition) eval(attrs map[string]interface{}) (bool, string) {
+	left, ok := cond.left.resolve(attrs)
+	if !ok {
+		return false, "attribute " + cond.left.attr + " is missing"
+	}
+	right, ok := cond.right.resolve(attrs)
+	if !ok {
+		return false, "attribute " + cond.right.attr + " is missing"
+	}
+
+	switch cond.op {
+	case "==":
+		return equal(left, right), ""
+	case "!=":
+		return !equal(left, right), ""
+	case "in":
+		return contains(right, left), ""
+	case "not in":
+		return !contains(right, left), ""
+	}
+
+	l, lok := toFloat(left)
+	r, rok := toFloat(right)
+	if !lok || !rok {
+		return false, "operator " + cond.op + " requires numbers"
+	}
+	switch cond.op {
+	case ">":
+		return l > r, ""
+	case ">=":
+		return l >= r, ""
+	case "<":
+		return l < r, ""
+	default:
+		return l <= r, ""
+	}
+}
+
+func (o operand) resolve(attrs map[string]interface{}) (interface{}, bool) {
+	if o.attr == "" {
+		return o.literal, true
+	}
+	var value interface{} = attrs
+	for _, part := range strings.Split(o.attr, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[part]; !ok || value == nil {
+			return nil, false
+		}
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return nil, false
+	}
+	return value, true
+}
+
+func equal(a, b interface{}) bool {
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		return ok && af == bf
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func contains(list, value interface{}) bool {
+	switch items := list.(type) {
+	case []string:
+		for _, item := range items {
+			if equal(item, value) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range items {
+			if equal(item, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,301 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Effets d'une règle
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Subject est l'utilisateur (ou la clé d'API) qui effectue l'action.
+type Subject struct {
+	UserID   string   `json:"user_id"`
+	Username string   `json:"username,omitempty"`
+	Role     string   `json:"role"`
+	TenantID string   `json:"tenant_id,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// Resource est l'objet visé par l'action. Les champs inconnus du gateway restent vides.
+type Resource struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	OwnerID    string                 `json:"owner_id,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// Input regroupe tout ce qu'une règle peut examiner.
+type Input struct {
+	Subject  Subject                `json:"subject"`
+	Action   string                 `json:"action" binding:"required"`
+	Resource Resource               `json:"resource"`
+	Request  map[string]interface{} `json:"request,omitempty"`
+}
+
+// attributes expose l'entrée sous forme d'arbre pour les conditions
+// (subject.role, resource.owner_id, request.content_length...).
+func (in Input) attributes() map[string]interface{} {
+	resource := map[string]interface{}{
+		"type":     in.Resource.Type,
+		"id":       in.Resource.ID,
+		"owner_id": in.Resource.OwnerID,
+	}
+	for k, v := range in.Resource.Attributes {
+		resource[k] = v
+	}
+	return map[string]interface{}{
+		"subject": map[string]interface{}{
+			"user_id":   in.Subject.UserID,
+			"username":  in.Subject.Username,
+			"role":      in.Subject.Role,
+			"tenant_id": in.Subject.TenantID,
+			"groups":    in.Subject.Groups,
+		},
+		"action":   in.Action,
+		"resource": resource,
+		"request":  in.Request,
+	}
+}
+
+// Rule est une règle du fichier de policy. Elle s'applique quand l'action, le rôle,
+// les groupes et toutes les conditions "when" correspondent.
+type Rule struct {
+	ID          string   `yaml:"id" json:"id"`
+	Description string   `yaml:"description" json:"description,omitempty"`
+	Effect      string   `yaml:"effect" json:"effect"`
+	Actions     []string `yaml:"actions" json:"actions"`
+	Roles       []string `yaml:"roles" json:"roles,omitempty"`
+	Groups      []string `yaml:"groups" json:"groups,omitempty"`
+	When        []string `yaml:"when" json:"when,omitempty"`
+
+	conditions []condition
+}
+
+// Policy est le contenu du fichier : un effet par défaut et des règles.
+type Policy struct {
+	Default string `yaml:"default"`
+	Rules   []Rule `yaml:"rules"`
+}
+
+// Parse lit et valide une policy YAML.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid policy: %v", err)
+	}
+	// Sans effet par défaut explicite, ce qui n'est pas autorisé est refusé
+	if p.Default == "" {
+		p.Default = Deny
+	}
+	if p.Default != Allow && p.Default != Deny {
+		return nil, fmt.Errorf("invalid policy: default must be allow or deny, got %q", p.Default)
+	}
+
+	seen := make(map[string]bool)
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i+1)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("invalid policy: duplicate rule id %q", rule.ID)
+		}
+		seen[rule.ID] = true
+		if rule.Effect != Allow && rule.Effect != Deny {
+			return nil, fmt.Errorf("invalid policy: rule %s: effect must be allow or deny", rule.ID)
+		}
+		if len(rule.Actions) == 0 {
+			return nil, fmt.Errorf("invalid policy: rule %s: at least one action is required", rule.ID)
+		}
+		for _, raw := range rule.When {
+			cond, err := parseCondition(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy: rule %s: %v", rule.ID, err)
+			}
+			rule.conditions = append(rule.conditions, cond)
+		}
+	}
+	return &p, nil
+}
+
+// Trace détaille l'évaluation d'une règle, pour l'endpoint explain.
+type Trace struct {
+	Rule    string `json:"rule"`
+	Effect  string `json:"effect"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Decision est le résultat d'une évaluation. Un deny l'emporte sur un allow ;
+// sans règle applicable, l'effet par défaut s'applique.
+type Decision struct {
+	Allowed bool    `json:"allowed"`
+	Rule    string  `json:"rule,omitempty"`
+	Reason  string  `json:"reason"`
+	Trace   []Trace `json:"trace"`
+}
+
+// Evaluate applique la policy à une entrée.
+func (p *Policy) Evaluate(in Input) Decision {
+	attrs := in.attributes()
+	decision := Decision{Trace: make([]Trace, 0, len(p.Rules))}
+	var allowedBy string
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		matched, reason := rule.matches(in, attrs)
+		decision.Trace = append(decision.Trace, Trace{Rule: rule.ID, Effect: rule.Effect, Matched: matched, Reason: reason})
+		if !matched {
+			continue
+		}
+		if rule.Effect == Deny && decision.Rule == "" {
+			decision.Rule = rule.ID
+		}
+		if rule.Effect == Allow && allowedBy == "" {
+			allowedBy = rule.ID
+		}
+	}
+
+	switch {
+	case decision.Rule != "":
+		decision.Reason = "denied by rule " + decision.Rule
+	case allowedBy != "":
+		decision.Allowed = true
+		decision.Rule = allowedBy
+		decision.Reason = "allowed by rule " + allowedBy
+	default:
+		decision.Allowed = p.Default == Allow
+		decision.Reason = "no matching rule, default " + p.Default
+	}
+	return decision
+}
+
+func (r *Rule) matches(in Input, attrs map[string]interface{}) (bool, string) {
+	if !matchAny(r.Actions, in.Action) {
+		return false, "action does not match"
+	}
+	if len(r.Roles) > 0 && !matchAny(r.Roles, in.Subject.Role) {
+		return false, "role does not match"
+	}
+	if len(r.Groups) > 0 && !intersects(r.Groups, in.Subject.Groups) {
+		return false, "groups do not match"
+	}
+	for _, cond := range r.conditions {
+		ok, reason := cond.eval(attrs)
+		if !ok {
+			if reason == "" {
+				reason = "condition is false"
+			}
+			return false, cond.raw + ": " + reason
+		}
+	}
+	return true, ""
+}
+
+// matchAny accepte les jokers "*" et "files:*".
+// References indique si une règle applicable à l'action examine l'attribut (ex.
+// resource.owner_id) : le coûteux n'est alors calculé que s'il sert.
+func (p *Policy) References(action, attribute string) bool {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !matchAny(rule.Actions, action) {
+			continue
+		}
+		for _, cond := range rule.conditions {
+			if cond.left.attr == attribute || cond.right.attr == attribute {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == value {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Engine détient la policy courante et la recharge depuis son fichier.
+// Sans fichier, tout est autorisé (seuls les rôles s'appliquent).
+type Engine struct {
+	path   string
+	mu     sync.RWMutex
+	policy *Policy
+}
+
+// NewEngine crée un moteur sans règle, qui autorise tout : sans POLICY_FILE, seuls
+// les rôles et les scopes s'appliquent. Un fichier sans "default" refuse par défaut.
+func NewEngine() *Engine {
+	return &Engine{policy: &Policy{Default: Allow}}
+}
+
+// Load crée un moteur à partir d'un fichier YAML.
+func Load(path string) (*Engine, error) {
+	e := &Engine{path: path}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload relit le fichier. En cas d'erreur, la policy précédente reste en place.
+func (e *Engine) Reload() error {
+	if e.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return fmt.Errorf("failed to read policy: %v", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.policy = p
+	e.mu.Unlock()
+	return nil
+}
+
+// Evaluate applique la policy courante.
+func (e *Engine) Evaluate(in Input) Decision {
+	e.mu.RLock()
+	p := e.policy
+	e.mu.RUnlock()
+	return p.Evaluate(in)
+}
+
+// References applique Policy.References à la policy courante.
+func (e *Engine) References(action, attribute string) bool {
+	e.mu.RLock()
+	p := e.policy
+	e.mu.RUnlock()
+	return p.References(action, attribute)
+}
@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPolicy = `
+default: allow
+rules:
+  - id: delete-own-files
+    description: Les utilisateurs ne suppriment que leurs fichiers, sauf le groupe ops
+    effect: deny
+    actions: ["files:delete"]
+    when:
+      - resource.owner_id != subject.user_id
+      - "'ops' not in subject.groups"
+  - id: readonly-upload-limit
+    effect: deny
+    actions: ["files:write", "files:import"]
+    roles: ["readonly"]
+    when:
+      - request.content_length > 10485760
+`
+
+func TestPolicyEvaluate(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	assert.NoError(t, err)
+
+	alice := Subject{UserID: "alice", Role: "user"}
+	ops := Subject{UserID: "bob", Role: "user", Groups: []string{"ops"}}
+
+	// Suppression de son propre fichier
+	d := p.Evaluate(Input{Subject: alice, Action: "files:delete", Resource: Resource{Type: "file", OwnerID: "alice"}})
+	assert.True(t, d.Allowed)
+
+	// Suppression du fichier d'un autre
+	d = p.Evaluate(Input{Subject: alice, Action: "files:delete", Resource: Resource{Type: "file", OwnerID: "carol"}})
+	assert.False(t, d.Allowed)
+	assert.Equal(t, "delete-own-files", d.Rule)
+
+	// Le groupe ops passe outre
+	d = p.Evaluate(Input{Subject: ops, Action: "files:delete", Resource: Resource{Type: "file", OwnerID: "carol"}})
+	assert.True(t, d.Allowed)
+
+	// Propriétaire inconnu : la condition ne s'applique pas
+	d = p.Evaluate(Input{Subject: alice, Action: "files:delete", Resource: Resource{Type: "file"}})
+	assert.True(t, d.Allowed)
+	assert.Contains(t, d.Trace[0].Reason, "resource.owner_id is missing")
+
+	// Rôle lecture seule : upload limité à 10MB
+	readonly := Subject{UserID: "dave", Role: "readonly"}
+	d = p.Evaluate(Input{Subject: readonly, Action: "files:write", Request: map[string]interface{}{"content_length": int64(20 << 20)}})
+	assert.False(t, d.Allowed)
+	assert.Equal(t, "readonly-upload-limit", d.Rule)
+
+	d = p.Evaluate(Input{Subject: readonly, Action: "files:write", Request: map[string]interface{}{"content_length": int64(1 << 20)}})
+	assert.True(t, d.Allowed)
+}
+
+func TestPolicyDenyOverridesAllow(t *testing.T) {
+	p, err := Parse([]byte(`
+default: deny
+rules:
+  - id: admins
+    effect: allow
+    actions: ["*"]
+    roles: ["admin"]
+  - id: no-admin-delete
+    effect: deny
+    actions: ["files:delete"]
+    roles: ["admin"]
+`))
+	assert.NoError(t, err)
+
+	admin := Subject{UserID: "1", Role: "admin"}
+	assert.True(t, p.Evaluate(Input{Subject: admin, Action: "files:read"}).Allowed)
+	assert.False(t, p.Evaluate(Input{Subject: admin, Action: "files:delete"}).Allowed)
+
+	d := p.Evaluate(Input{Subject: Subject{UserID: "2", Role: "user"}, Action: "files:read"})
+	assert.False(t, d.Allowed)
+	assert.Equal(t, "no matching rule, default deny", d.Reason)
+}
+
+func TestParseDefaultsToDeny(t *testing.T) {
+	p, err := Parse([]byte("rules:\n  - effect: allow\n    actions: [\"files:read\"]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, Deny, p.Default)
+	assert.True(t, p.Evaluate(Input{Action: "files:read"}).Allowed)
+	assert.False(t, p.Evaluate(Input{Action: "files:delete"}).Allowed)
+}
+
+func TestParseRejectsInvalidPolicy(t *testing.T) {
+	_, err := Parse([]byte("rules:\n  - effect: maybe\n    actions: [\"*\"]\n"))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte("rules:\n  - effect: deny\n    actions: [\"*\"]\n    when: [\"subject.role ~ 'x'\"]\n"))
+	assert.Error(t, err)
+}
+
+func TestEngineReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("default: allow\n"), 0600))
+
+	engine, err := Load(path)
+	assert.NoError(t, err)
+	in := Input{Subject: Subject{UserID: "1", Role: "user"}, Action: "files:read"}
+	assert.True(t, engine.Evaluate(in).Allowed)
+
+	assert.NoError(t, os.WriteFile(path, []byte("default: deny\n"), 0600))
+	assert.NoError(t, engine.Reload())
+	assert.False(t, engine.Evaluate(in).Allowed)
+
+	// Un fichier invalide ne remplace pas la policy en place
+	assert.NoError(t, os.WriteFile(path, []byte("default: [\n"), 0600))
+	assert.Error(t, engine.Reload())
+	assert.False(t, engine.Evaluate(in).Allowed)
+}
@@ -0,0 +1,61 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// ErrQuotaExceeded est retourné quand un upload dépasserait le quota de l'utilisateur.
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Tracker suit l'espace de stockage consommé par chaque utilisateur, au sein de son tenant.
+type Tracker struct {
+	store store.Store
+	limit int64
+}
+
+// New crée un tracker avec une limite en octets par utilisateur (0 = illimité).
+func New(st store.Store, limit int64) *Tracker {
+	return &Tracker{store: st, limit: limit}
+}
+
+func usageKey(tenantID, userID string) string {
+	return fmt.Sprintf("quota:usage:%s:%s", tenantID, userID)
+}
+
+// Reserve comptabilise size octets pour l'utilisateur, ou échoue si le quota est dépassé.
+func (t *Tracker) Reserve(ctx context.Context, tenantID, userID string, size int64) error {
+	used, err := t.store.IncrBy(ctx, usageKey(tenantID, userID), size)
+	if err != nil {
+		return err
+	}
+	if t.limit > 0 && used > t.limit {
+		// Annuler la réservation
+		t.store.IncrBy(ctx, usageKey(tenantID, userID), -size)
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// Release libère size octets du quota de l'utilisateur.
+func (t *Tracker) Release(ctx context.Context, tenantID, userID string, size int64) error {
+	_, err := t.store.IncrBy(ctx, usageKey(tenantID, userID), -size)
+	return err
+}
+
+// Usage retourne l'espace consommé et la limite de l'utilisateur.
+func (t *Tracker) Usage(ctx context.Context, tenantID, userID string) (used int64, limit int64, err error) {
+	value, err := t.store.Get(ctx, usageKey(tenantID, userID))
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, t.limit, nil
+	}
+	if err != nil {
+		return 0, t.limit, err
+	}
+	used, err = strconv.ParseInt(value, 10, 64)
+	return used, t.limit, err
+}
@@ -1,12 +1,44 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/accounts"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/apikeys"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/audit"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/clientcert"
 	"github.com/mtk14m/mini-cloud/api-gateway/internal/config"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/filerequests"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/files"
 	"github.com/mtk14m/mini-cloud/api-gateway/internal/handlers"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/importer"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/jobs"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/lockout"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/mailer"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/mfa"
 	"github.com/mtk14m/mini-cloud/api-gateway/internal/middleware"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/netguard"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/oidc"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/policy"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/quota"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/sessions"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tenants"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/tokens"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/users"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/validation"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/webhooks"
+	"github.com/mtk14m/mini-cloud/api-gateway/pkg/identity"
 )
 
 type Server struct {
@@ -21,22 +53,36 @@ func New(cfg *config.Config) *Server {
 
 	router := gin.New()
 
+	// X-Forwarded-For n'est lu que derrière ces proxies : sinon ClientIP, utilisé par
+	// le verrouillage par IP et le rate limiting, serait falsifiable par le client
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid trusted proxies configuration: ", err)
+	}
+
 	//Middlewares global
 	router.Use(gin.Recovery())
-	router.Use(middleware.Cors())
+	router.Use(middleware.Cors(corsOrigins(cfg)))
+	router.Use(middleware.RequestID())
 	router.Use(gin.Logger())
 
 	//on va desactivé le ratelimiting en mode debug
-	if cfg.RedisURL != "" && !strings.Contains(cfg.RedisURL, "localhost") {
-		rateLimiter := middleware.NewRateLimiter(
+	var rateLimiter *middleware.RateLimiter
+	if redisEnabled(cfg) {
+		rateLimiter = middleware.NewRateLimiter(
 			cfg.RedisURL,
 			cfg.RateLimit,
 		)
 		router.Use(rateLimiter.RateLimit())
 	}
 
+	//Stockage partagé : Redis en production, mémoire en mode debug
+	var st store.Store = store.NewMemory()
+	if redisEnabled(cfg) {
+		st = store.NewRedis(cfg.RedisURL)
+	}
+
 	// Routes
-	setupRoutes(router, cfg)
+	setupRoutes(router, cfg, st, rateLimiter)
 
 	return &Server{
 		router: router,
@@ -44,38 +90,476 @@ func New(cfg *config.Config) *Server {
 	}
 }
 
-func setupRoutes(router *gin.Engine, cfg *config.Config) {
+// redisEnabled indique si un Redis distant est configuré (hors mode debug).
+func redisEnabled(cfg *config.Config) bool {
+	return cfg.RedisURL != "" && !strings.Contains(cfg.RedisURL, "localhost")
+}
+
+func setupRoutes(router *gin.Engine, cfg *config.Config, st store.Store, rateLimiter *middleware.RateLimiter) {
+
+	quotas := quota.New(st, int64(cfg.UserQuota))
+	inbox := notify.NewInbox(st)
+	fileRequests := filerequests.NewRepository(st)
+	auditLog := audit.NewLog(st)
+	apiKeys := apikeys.NewRepository(st)
+	upstream := newUpstreamClient(cfg)
+	// Le propriétaire n'est demandé au service de fichiers que si une règle l'examine
+	fileResource := middleware.OwnedResource("file", "id", fileOwner(files.NewClient(cfg.FileServiceURL, upstream)))
+	directory := loadUserDirectory(cfg)
+	authDeps := &handlers.AuthDeps{
+		Keys:          loadKeySet(cfg),
+		RefreshTokens: tokens.NewRefreshStore(st, cfg.RefreshTokenTTL),
+		Denylist:      tokens.NewDenylist(st, cfg.AccessTokenTTL, cfg.DenylistCacheTTL),
+		OIDC:          oidc.NewRegistry(cfg.OIDCProviders, st, nil),
+		Tenants:       tenants.NewRepository(st),
+		MFA:           mfa.NewService(st, cfg.MFAIssuer, cfg.MFAChallengeTTL, cfg.MFARequiredRoles),
+		Lockout: lockout.NewGuard(st, lockout.Options{
+			MaxFailures:     cfg.LoginMaxFailures,
+			MaxIPFailures:   cfg.LoginIPMaxFailures,
+			Window:          cfg.LoginFailureWindow,
+			LockoutDuration: cfg.LoginLockoutDuration,
+			BaseDelay:       cfg.LoginDelayBase,
+			MaxDelay:        cfg.LoginDelayMax,
+		}, auditLog),
+		Audit:        auditLog,
+		Registration: loadRegistrationRules(cfg),
+		Accounts:     accounts.NewRepository(st),
+		ActionTokens: tokens.NewActionTokens(st, cfg.JWT_SECRET),
+		Mailer:       newMailer(cfg),
+		APIKeys:      apiKeys,
+		Sessions:     sessions.NewRepository(st, cfg.RefreshTokenTTL),
+		// Demandes de réinitialisation, comptées par IP et par adresse email
+		ResetThrottle: lockout.NewThrottle(st, cfg.PasswordResetLimit, cfg.PasswordResetWindow),
+		Claims: tokens.ClaimsConfig{
+			Issuer:   cfg.JWTIssuer,
+			Audience: cfg.JWTAudience,
+			Leeway:   cfg.JWTLeeway,
+			// Tokens émis avant iss/aud : acceptés seulement jusqu'à la date fixée par
+			// JWT_LEGACY_ACCEPT_UNTIL, refusés si elle n'est pas définie
+			LegacyUntil: cfg.JWTLegacyUntil,
+		},
+	}
+	if directory != nil {
+		authDeps.Provider = handlers.NewEmbeddedProvider(directory, cfg.UserDefaultRole)
+	}
+	permissions := middleware.Permissions(cfg.RolePermissions)
+	policies := loadPolicy(cfg)
+	jobRepo := jobs.NewRepository(st)
+	webhookRepo := webhooks.NewRepository(st)
+	webhookGuard, err := netguard.New(cfg.WebhookAllowlist)
+	if err != nil {
+		log.Fatal("Invalid webhook configuration: ", err)
+	}
+	dispatcher := webhooks.NewDispatcher(webhookRepo, st, webhooks.Options{
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		RetryDelay:  time.Duration(cfg.WebhookRetryDelay) * time.Second,
+		Timeout:     time.Duration(cfg.WebhookTimeout) * time.Second,
+		Guard:       webhookGuard,
+	})
+
+	//Les évènements vont dans la boîte de notifications et aux webhooks
+	events := notify.Multi(inbox, dispatcher)
+
+	imp, err := importer.New(importer.Options{
+		MaxSize:   int64(cfg.MaxUploadSize),
+		Timeout:   time.Duration(cfg.ImportTimeout) * time.Second,
+		Allowlist: cfg.ImportAllowlist,
+	}, jobRepo, quotas, events)
+	if err != nil {
+		log.Fatal("Invalid import configuration: ", err)
+	}
 
 	//Health check
 	router.GET("/health", handlers.HealthCheck)
 
+	//Clés publiques de vérification des tokens
+	router.GET("/.well-known/jwks.json", handlers.JWKS(authDeps.Keys))
+
 	//API v1
 	v1 := router.Group("/api/v1")
 	{
 		//Authentication
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/login", handlers.Login(cfg))
-			auth.POST("/register", handlers.Register(cfg))
-			auth.POST("/validate", handlers.Validate(cfg))
+			auth.POST("/login", handlers.Login(cfg, authDeps, upstream))
+			auth.POST("/refresh", handlers.Refresh(cfg, authDeps))
+			auth.POST("/register", handlers.Register(cfg, authDeps, events, upstream))
+			auth.POST("/introspect", handlers.Introspect(cfg, authDeps))
+			// Ancien nom, conservé pour les services existants
+			auth.POST("/validate", handlers.Introspect(cfg, authDeps))
+			//Forward auth pour les applications derrière l'ingress
+			auth.Any("/forward", handlers.ForwardAuth(cfg, authDeps))
+			auth.POST("/verify-email", handlers.VerifyEmail(authDeps))
+			auth.POST("/forgot-password", handlers.ForgotPassword(cfg, authDeps))
+			auth.POST("/reset-password", handlers.ResetPassword(cfg, authDeps, upstream))
+
+			//Second facteur (TOTP)
+			auth.POST("/mfa/verify", handlers.MFAVerify(cfg, authDeps))
+			auth.POST("/mfa/enroll", handlers.MFAEnrollChallenge(authDeps))
+
+			//Connexion via un fournisseur OIDC externe
+			auth.GET("/oidc/:provider/login", handlers.OIDCLogin(cfg, authDeps))
+			auth.GET("/oidc/:provider/callback", handlers.OIDCCallback(cfg, authDeps))
 		}
 
-		//services protégés
+		//Routes publiques (sans compte)
+		public := v1.Group("/public")
+		{
+			public.GET("/file-requests/:token", handlers.GetPublicFileRequest(fileRequests, events))
+			public.POST("/file-requests/:token/upload", middleware.Decompress(int64(cfg.MaxUploadSize)), handlers.UploadToFileRequest(cfg, fileRequests, quotas, events))
+		}
+
+		//services protégés (JWT ou clé d'API ; les scopes ne concernent que les clés)
 		protected := v1.Group("/")
-		protected.Use(middleware.Auth(cfg.JWT_SECRET))
+		protected.Use(middleware.Auth(cfg.JWT_SECRET,
+			middleware.WithKeySet(authDeps.Keys),
+			middleware.WithClaims(authDeps.Claims),
+			middleware.WithDenylist(authDeps.Denylist),
+			middleware.WithSessions(authDeps.Sessions),
+			middleware.WithAPIKeys(apiKeys),
+			middleware.WithMembership(authDeps.Tenants),
+			middleware.RequireTenant(),
+			middleware.WithSessionCookie(cfg.AccessTokenCookie, cfg.CSRFCookie),
+			middleware.WithClientCerts(loadClientCertMapper(cfg), cfg.ClientCertRoutes),
+			middleware.WithImpersonation(auditLog, cfg.ImpersonationBlocked),
+		))
+		protected.Use(middleware.PropagateIdentity())
+		//Limite globale par tenant
+		if rateLimiter != nil && cfg.TenantRateLimit > 0 {
+			protected.Use(rateLimiter.PerTenant(cfg.TenantRateLimit))
+		}
 		{
+			//Session
+			protected.POST("/auth/logout", handlers.Logout(cfg, authDeps))
+			protected.GET("/auth/sessions", middleware.RequireSession(), handlers.ListSessions(authDeps))
+			protected.DELETE("/auth/sessions", middleware.RequireSession(), middleware.DenyImpersonation(), handlers.RevokeSessions(cfg, authDeps))
+			protected.DELETE("/auth/sessions/:id", middleware.RequireSession(), middleware.DenyImpersonation(), handlers.RevokeSession(cfg, authDeps))
+			protected.POST("/auth/verify-email/resend", middleware.RequireSession(), middleware.DenyImpersonation(), handlers.ResendVerificationEmail(cfg, authDeps))
+
+			//MFA de l'utilisateur
+			mfaGroup := protected.Group("/mfa", middleware.RequireSession(), middleware.DenyImpersonation())
+			{
+				mfaGroup.GET("", handlers.GetMFAStatus(authDeps))
+				mfaGroup.POST("/enroll", handlers.EnrollMFA(authDeps))
+				mfaGroup.POST("/confirm", handlers.ConfirmMFA(authDeps))
+				mfaGroup.POST("/recovery-codes", handlers.RegenerateRecoveryCodes(authDeps))
+				mfaGroup.POST("/disable", handlers.DisableMFA(authDeps))
+			}
+
+			//Administration
+			admin := protected.Group("/admin", middleware.RequireRole("admin"), middleware.RequireScope("admin"))
+			{
+				admin.POST("/users/:user_id/revoke-tokens", handlers.RevokeUserTokens(authDeps))
+				admin.GET("/mfa/required-roles", handlers.GetMFARequiredRoles(authDeps))
+				admin.PUT("/mfa/required-roles", handlers.SetMFARequiredRoles(authDeps))
+				admin.POST("/login-lockouts/unlock", handlers.UnlockLogin(authDeps))
+				admin.GET("/audit", handlers.ListAuditLog(authDeps))
+				admin.POST("/impersonate/:user_id", middleware.RequireSession(), handlers.Impersonate(cfg, authDeps))
+				if directory != nil {
+					admin.GET("/users", handlers.ListUsers(directory))
+					admin.PUT("/users/:user_id/role", handlers.SetUserRole(cfg, authDeps, directory))
+				}
+			}
+
+			//Membres du tenant (admins du tenant)
+			members := protected.Group("/tenant/members", middleware.RequirePermission(permissions, "tenant:members"), middleware.RequireScope("admin"))
+			{
+				members.GET("", handlers.ListTenantMembers(authDeps))
+				members.PUT("/:user_id", handlers.UpdateTenantMember(authDeps))
+				members.DELETE("/:user_id", handlers.RemoveTenantMember(authDeps))
+				members.POST("/:user_id/restore", handlers.RestoreTenantMember(authDeps))
+			}
+
 			//Fichiers
 			files := protected.Group("/files")
 			{
-				files.POST("/upload", handlers.UploadFile(cfg))
-				files.GET("/:id", handlers.DownloadFile(cfg))
-				files.DELETE("/:id", handlers.DeleteFile(cfg))
+				files.POST("/upload",
+					middleware.RequirePermission(permissions, "files:write"),
+					middleware.RequireScope("files:write"),
+					middleware.Policy(policies, "files:write", nil),
+					middleware.Decompress(int64(cfg.MaxUploadSize)),
+					handlers.UploadFile(cfg, quotas, events))
+				files.GET("/:id",
+					middleware.RequirePermission(permissions, "files:read"),
+					middleware.RequireScope("files:read"),
+					middleware.Policy(policies, "files:read", fileResource),
+					middleware.Compress(),
+					handlers.DownloadFile(cfg))
+				files.DELETE("/:id",
+					middleware.RequirePermission(permissions, "files:delete"),
+					middleware.RequireScope("files:write"),
+					middleware.Policy(policies, "files:delete", fileResource),
+					handlers.DeleteFile(cfg, events))
+				files.POST("/import",
+					middleware.RequirePermission(permissions, "files:write"),
+					middleware.RequireScope("files:write"),
+					middleware.Policy(policies, "files:import", nil),
+					handlers.ImportFile(imp))
+			}
+
+			//Tâches de fond
+			protected.GET("/jobs/:id", middleware.RequirePermission(permissions, "files:read"), middleware.RequireScope("files:read"), handlers.GetJob(jobRepo))
+
+			//Policy : simulation d'une décision
+			protected.POST("/policy/explain", handlers.ExplainPolicy(policies))
+
+			//Clés d'API
+			apiKeysGroup := protected.Group("/api-keys", middleware.RequireSession(), middleware.DenyImpersonation())
+			{
+				apiKeysGroup.POST("", handlers.CreateAPIKey(apiKeys))
+				apiKeysGroup.GET("", handlers.ListAPIKeys(apiKeys))
+				apiKeysGroup.DELETE("/:id", handlers.RevokeAPIKey(apiKeys))
+			}
+
+			//Liens de dépôt
+			fileRequestsGroup := protected.Group("/file-requests")
+			{
+				fileRequestsGroup.POST("", middleware.RequireScope("file_requests:write"), handlers.CreateFileRequest(fileRequests))
+				fileRequestsGroup.GET("", middleware.RequireScope("file_requests:read"), handlers.ListFileRequests(fileRequests))
+				fileRequestsGroup.DELETE("/:token", middleware.RequireScope("file_requests:write"), handlers.DeleteFileRequest(fileRequests))
+			}
+
+			//Notifications
+			protected.GET("/notifications", middleware.RequireScope("notifications:read"), handlers.ListNotifications(inbox))
+
+			//Webhooks
+			webhooksGroup := protected.Group("/webhooks", middleware.DenyImpersonation())
+			{
+				webhooksGroup.POST("", middleware.RequireScope("webhooks:write"), handlers.CreateWebhook(webhookRepo, webhookGuard))
+				webhooksGroup.GET("", middleware.RequireScope("webhooks:read"), handlers.ListWebhooks(webhookRepo))
+				webhooksGroup.GET("/dead-letters", middleware.RequireScope("webhooks:read"), handlers.ListWebhookDeadLetters(dispatcher))
+				webhooksGroup.DELETE("/:id", middleware.RequireScope("webhooks:write"), handlers.DeleteWebhook(webhookRepo))
+				webhooksGroup.GET("/:id/deliveries", middleware.RequireScope("webhooks:read"), handlers.ListWebhookDeliveries(webhookRepo, dispatcher))
+			}
+		}
+
+	}
+}
+
+// corsOrigins retourne les origines autorisées par CORS_ALLOWED_ORIGINS ; par défaut,
+// celle du frontend (PUBLIC_URL).
+func corsOrigins(cfg *config.Config) []string {
+	if len(cfg.CORSAllowedOrigins) > 0 {
+		return cfg.CORSAllowedOrigins
+	}
+	return []string{strings.TrimSuffix(cfg.PublicURL, "/")}
+}
+
+// loadKeySet charge les clés de signature configurées. Sans clé, on reste en HS256.
+// Les fichiers sont relus périodiquement pour suivre les rotations.
+func loadKeySet(cfg *config.Config) *tokens.KeySet {
+	if len(cfg.JWTKeys) == 0 {
+		return tokens.NewHMACKeySet(cfg.JWT_SECRET)
+	}
+
+	specs := make([]tokens.KeySpec, 0, len(cfg.JWTKeys))
+	for _, value := range cfg.JWTKeys {
+		spec, err := tokens.ParseKeySpec(value)
+		if err != nil {
+			log.Fatal("Invalid JWT key configuration: ", err)
+		}
+		specs = append(specs, spec)
+	}
+
+	keys, err := tokens.LoadKeySet(specs, cfg.JWT_SECRET, cfg.JWTHS256AcceptUntil)
+	if err != nil {
+		log.Fatal("Failed to load JWT keys: ", err)
+	}
+
+	if cfg.JWTKeysReloadInterval > 0 {
+		go func() {
+			for range time.Tick(cfg.JWTKeysReloadInterval) {
+				if err := keys.Reload(); err != nil {
+					log.Printf("Failed to reload JWT keys: %v", err)
+				}
 			}
+		}()
+	}
+	return keys
+}
+
+// fileOwner résout le propriétaire d'un fichier auprès du service de fichiers ; un
+// fichier inconnu est refusé par la policy.
+func fileOwner(client *files.Client) middleware.OwnerFunc {
+	return func(ctx context.Context, id string) (string, error) {
+		owner, err := client.Owner(ctx, id)
+		if errors.Is(err, files.ErrNotFound) {
+			return "", middleware.ErrUnknownOwner
 		}
+		return owner, err
+	}
+}
 
+// loadPolicy charge les règles d'autorisation déclaratives. Sans fichier, seules
+// les permissions des rôles s'appliquent. Le fichier est relu périodiquement.
+func loadPolicy(cfg *config.Config) *policy.Engine {
+	if cfg.PolicyFile == "" {
+		return policy.NewEngine()
 	}
+
+	engine, err := policy.Load(cfg.PolicyFile)
+	if err != nil {
+		log.Fatal("Failed to load policy: ", err)
+	}
+
+	if cfg.PolicyReloadInterval > 0 {
+		go func() {
+			for range time.Tick(cfg.PolicyReloadInterval) {
+				if err := engine.Reload(); err != nil {
+					log.Printf("Failed to reload policy: %v", err)
+				}
+			}
+		}()
+	}
+	return engine
+}
+
+// newMailer envoie les emails par SMTP si un serveur est configuré, sinon dans
+// MAIL_DIR ou dans les logs (développement).
+func newMailer(cfg *config.Config) mailer.Mailer {
+	if cfg.SMTPHost == "" {
+		return mailer.NewFile(cfg.MailDir, cfg.MailFrom)
+	}
+	return mailer.NewSMTP(mailer.SMTPOptions{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.MailFrom,
+	})
 }
 
+// loadRegistrationRules construit les règles d'inscription ; une configuration
+// invalide empêche le démarrage.
+func loadRegistrationRules(cfg *config.Config) *validation.Rules {
+	pattern, err := regexp.Compile(cfg.UsernamePattern)
+	if err != nil {
+		log.Fatal("Invalid USERNAME_PATTERN: ", err)
+	}
+	rules := &validation.Rules{
+		UsernameMinLength:  cfg.UsernameMinLength,
+		UsernameMaxLength:  cfg.UsernameMaxLength,
+		UsernamePattern:    pattern,
+		PasswordMinLength:  cfg.PasswordMinLength,
+		PasswordMaxLength:  cfg.PasswordMaxLength,
+		PasswordMinClasses: cfg.PasswordMinClasses,
+	}
+	if cfg.BreachedPasswordsFile != "" {
+		rules.Breached, err = validation.LoadBreached(cfg.BreachedPasswordsFile)
+		if err != nil {
+			log.Fatal("Failed to load breached passwords: ", err)
+		}
+	}
+	return rules
+}
+
+// loadUserDirectory ouvre l'annuaire embarqué quand AUTH_PROVIDER=embedded, et crée
+// le compte admin initial (BOOTSTRAP_ADMIN_USERNAME) s'il n'existe pas encore.
+// Retourne nil pour le service d'authentification distant.
+func loadUserDirectory(cfg *config.Config) *users.Repository {
+	switch cfg.AuthProvider {
+	case "remote":
+		return nil
+	case "embedded":
+	default:
+		log.Fatalf("Invalid AUTH_PROVIDER %q: expected remote or embedded", cfg.AuthProvider)
+	}
+	if _, known := cfg.RolePermissions[cfg.UserDefaultRole]; !known {
+		log.Fatalf("Invalid USER_DEFAULT_ROLE %q: no permissions defined", cfg.UserDefaultRole)
+	}
+
+	hasher, err := users.NewHasher(cfg.PasswordHashAlgorithm)
+	if err != nil {
+		log.Fatal("Invalid PASSWORD_HASH_ALGORITHM: ", err)
+	}
+	ctx := context.Background()
+	repo, err := users.Open(ctx, cfg.UserDBDriver, cfg.UserDBDSN, hasher)
+	if err != nil {
+		log.Fatal("Failed to open user database: ", err)
+	}
+
+	if cfg.BootstrapAdmin == "" {
+		return repo
+	}
+	_, err = repo.FindByUsername(ctx, cfg.BootstrapAdmin)
+	if errors.Is(err, users.ErrNotFound) {
+		if cfg.BootstrapPassword == "" {
+			log.Fatal("BOOTSTRAP_ADMIN_PASSWORD is required to create the admin account")
+		}
+		admin := &users.User{Username: cfg.BootstrapAdmin, Role: "admin"}
+		err = repo.Create(ctx, admin, cfg.BootstrapPassword)
+	}
+	if err != nil {
+		log.Fatal("Failed to create admin account: ", err)
+	}
+	return repo
+}
+
+// Run démarre le serveur, en TLS si un certificat est configuré. TLS_CLIENT_AUTH
+// ("none", "optional", "required") active la vérification des certificats clients
+// par le bundle TLS_CLIENT_CA_FILE.
 func (s *Server) Run() error {
-	return s.router.Run(":" + s.config.Port)
+	if s.config.TLSCertFile == "" {
+		return s.router.Run(":" + s.config.Port)
+	}
+
+	tlsConfig, err := loadTLSConfig(s.config)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:      ":" + s.config.Port,
+		Handler:   s.router,
+		TLSConfig: tlsConfig,
+	}
+	return srv.ListenAndServeTLS(s.config.TLSCertFile, s.config.TLSKeyFile)
+}
+
+func loadTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	switch cfg.TLSClientAuth {
+	case "", "none":
+		return tlsConfig, nil
+	case "optional":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case "required":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("invalid TLS_CLIENT_AUTH %q", cfg.TLSClientAuth)
+	}
+	if cfg.TLSClientCAFile == "" {
+		return nil, fmt.Errorf("TLS_CLIENT_CA_FILE is required when TLS_CLIENT_AUTH is %q", cfg.TLSClientAuth)
+	}
+	pool, err := clientcert.LoadCAPool(cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, nil
+}
+
+// newUpstreamClient crée le client des appels aux services internes : les headers
+// d'identité fournis par le client sont remplacés par l'identité signée de la requête.
+func newUpstreamClient(cfg *config.Config) *http.Client {
+	if cfg.IdentitySigningSecret == "" {
+		log.Println("IDENTITY_SIGNING_SECRET is not set: identity headers sent to upstream services are not signed")
+	}
+	return &http.Client{
+		Transport: &identity.Transport{Signer: identity.NewSigner(cfg.IdentitySigningSecret)},
+		Timeout:   30 * time.Second,
+	}
+}
+
+// loadClientCertMapper construit la table certificat -> identité ; une entrée
+// invalide empêche le démarrage.
+func loadClientCertMapper(cfg *config.Config) *clientcert.Mapper {
+	identities := make([]clientcert.Identity, 0, len(cfg.ClientCertIdentities))
+	for _, entry := range cfg.ClientCertIdentities {
+		identity, err := clientcert.ParseIdentity(entry)
+		if err != nil {
+			log.Fatal("Invalid CLIENT_CERT_IDENTITIES: ", err)
+		}
+		identities = append(identities, identity)
+	}
+	return clientcert.NewMapper(identities)
 }
@@ -0,0 +1,198 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// Intervalle minimal entre deux mises à jour de LastSeenAt, pour éviter une
+// écriture dans le store à chaque requête
+const lastSeenResolution = time.Minute
+
+// ErrNotFound est retourné quand la session n'existe pas ou n'appartient pas à l'utilisateur.
+var ErrNotFound = errors.New("session not found")
+
+// Session est une connexion d'un utilisateur sur un appareil. Son identifiant est
+// celui de la famille de refresh tokens et le claim "sid" des tokens d'accès.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	TenantID   string    `json:"tenant_id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Repository persiste les sessions ; elles expirent avec leur dernier refresh token.
+type Repository struct {
+	store store.Store
+	ttl   time.Duration
+}
+
+// NewRepository crée un repository dont les sessions inactives expirent après ttl
+// (la durée de vie des refresh tokens).
+func NewRepository(st store.Store, ttl time.Duration) *Repository {
+	return &Repository{store: st, ttl: ttl}
+}
+
+func sessionKey(id string) string {
+	return fmt.Sprintf("session:%s", id)
+}
+
+func userKey(userID string) string {
+	return fmt.Sprintf("sessions:user:%s", userID)
+}
+
+// Create enregistre une nouvelle session.
+func (r *Repository) Create(ctx context.Context, session *Session) error {
+	now := time.Now().UTC()
+	session.Device = DeviceName(session.UserAgent)
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	if err := r.save(ctx, session); err != nil {
+		return err
+	}
+	return r.store.SAdd(ctx, userKey(session.UserID), session.ID)
+}
+
+// Get retourne une session de l'utilisateur.
+func (r *Repository) Get(ctx context.Context, userID, id string) (*Session, error) {
+	var session Session
+	if err := store.GetJSON(ctx, r.store, sessionKey(id), &session); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+// List retourne les sessions actives de l'utilisateur, la plus récente d'abord.
+func (r *Repository) List(ctx context.Context, userID string) ([]Session, error) {
+	ids, err := r.store.SMembers(ctx, userKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, 0, len(ids))
+	for _, id := range ids {
+		session, err := r.Get(ctx, userID, id)
+		if errors.Is(err, ErrNotFound) {
+			// Session expirée : nettoyer l'index
+			r.store.SRem(ctx, userKey(userID), id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+	sortByLastSeen(sessions)
+	return sessions, nil
+}
+
+// Touch met à jour la date de dernière activité et l'IP, au plus une fois par minute.
+func (r *Repository) Touch(ctx context.Context, userID, id, ip string) error {
+	session, err := r.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if now.Sub(session.LastSeenAt) < lastSeenResolution && session.IP == ip {
+		return nil
+	}
+	session.LastSeenAt = now
+	session.IP = ip
+	return r.save(ctx, session)
+}
+
+// Delete supprime une session de l'utilisateur.
+func (r *Repository) Delete(ctx context.Context, userID, id string) error {
+	if err := r.store.Delete(ctx, sessionKey(id)); err != nil {
+		return err
+	}
+	return r.store.SRem(ctx, userKey(userID), id)
+}
+
+// DeleteUser supprime toutes les sessions de l'utilisateur.
+func (r *Repository) DeleteUser(ctx context.Context, userID string) error {
+	ids, err := r.store.SMembers(ctx, userKey(userID))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := r.store.Delete(ctx, sessionKey(id)); err != nil {
+			return err
+		}
+	}
+	return r.store.Delete(ctx, userKey(userID))
+}
+
+func (r *Repository) save(ctx context.Context, session *Session) error {
+	return store.SetJSON(ctx, r.store, sessionKey(session.ID), session, r.ttl)
+}
+
+func sortByLastSeen(sessions []Session) {
+	for i := 1; i < len(sessions); i++ {
+		for j := i; j > 0 && sessions[j].LastSeenAt.After(sessions[j-1].LastSeenAt); j-- {
+			sessions[j], sessions[j-1] = sessions[j-1], sessions[j]
+		}
+	}
+}
+
+// DeviceName résume le user agent en « navigateur sur système », ex. "Firefox on Linux".
+func DeviceName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+	browser := ""
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	system := ""
+	for _, s := range []struct{ token, name string }{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	// Client inconnu (SDK, script) : garder le début du user agent
+	if len(userAgent) > 64 {
+		return userAgent[:64]
+	}
+	return userAgent
+}
@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value     string
+	set       map[string]struct{}
+	list      []string
+	expiresAt time.Time
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryStore implémente Store en mémoire, pour le développement et les tests.
+// Les données ne sont pas partagées entre les réplicas du gateway.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+// NewMemory crée un store en mémoire vide.
+func NewMemory() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+// entry retourne l'entrée vivante pour key, ou nil. Le verrou doit être tenu.
+func (s *MemoryStore) entry(key string) *memoryEntry {
+	e, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	if e.expired(time.Now()) {
+		delete(s.entries, key)
+		return nil
+	}
+	return e
+}
+
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(key)
+	if e == nil || e.set != nil || e.list != nil {
+		return "", ErrNotFound
+	}
+	return e.value, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = &memoryEntry{value: value, expiresAt: expiry(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entry(key) != nil {
+		return false, nil
+	}
+	s.entries[key] = &memoryEntry{value: value, expiresAt: expiry(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) IncrBy(ctx context.Context, key string, n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(key)
+	if e == nil {
+		e = &memoryEntry{value: "0"}
+		s.entries[key] = e
+	}
+	current, err := strconv.ParseInt(e.value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value at %s is not an integer", key)
+	}
+	current += n
+	e.value = strconv.FormatInt(current, 10)
+	return current, nil
+}
+
+func (s *MemoryStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e := s.entry(key); e != nil {
+		e.expiresAt = expiry(ttl)
+	}
+	return nil
+}
+
+func (s *MemoryStore) SAdd(ctx context.Context, key string, members ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(key)
+	if e == nil || e.set == nil {
+		e = &memoryEntry{set: make(map[string]struct{})}
+		s.entries[key] = e
+	}
+	for _, m := range members {
+		e.set[m] = struct{}{}
+	}
+	return nil
+}
+
+func (s *MemoryStore) SRem(ctx context.Context, key string, members ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(key)
+	if e == nil || e.set == nil {
+		return nil
+	}
+	for _, m := range members {
+		delete(e.set, m)
+	}
+	return nil
+}
+
+func (s *MemoryStore) SMembers(ctx context.Context, key string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(key)
+	if e == nil || e.set == nil {
+		return []string{}, nil
+	}
+	members := make([]string, 0, len(e.set))
+	for m := range e.set {
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func (s *MemoryStore) LPush(ctx context.Context, key, value string, max int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(key)
+	if e == nil || e.list == nil {
+		e = &memoryEntry{list: []string{}}
+		s.entries[key] = e
+	}
+	e.list = append([]string{value}, e.list...)
+	if max > 0 && len(e.list) > max {
+		e.list = e.list[:max]
+	}
+	return nil
+}
+
+func (s *MemoryStore) LRange(ctx context.Context, key string, start, stop int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(key)
+	if e == nil || e.list == nil {
+		return []string{}, nil
+	}
+	n := len(e.list)
+	// Indices négatifs comme Redis (-1 = dernier élément)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop {
+		return []string{}, nil
+	}
+	out := make([]string, stop-start+1)
+	copy(out, e.list[start:stop+1])
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+
+	s.Set(ctx, "short", "value", 10*time.Millisecond)
+	s.Set(ctx, "long", "value", 0)
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, err := s.Get(ctx, "short")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	value, err := s.Get(ctx, "long")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
+
+func TestMemoryStoreExpire(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+
+	s.IncrBy(ctx, "counter", 1)
+	assert.NoError(t, s.Expire(ctx, "counter", 10*time.Millisecond))
+	// Sans effet sur une clé absente
+	assert.NoError(t, s.Expire(ctx, "missing", time.Minute))
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, err := s.Get(ctx, "counter")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryStoreList(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+
+	for _, v := range []string{"a", "b", "c"} {
+		s.LPush(ctx, "list", v, 2)
+	}
+
+	items, err := s.LRange(ctx, "list", 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "b"}, items)
+}
+
+func TestMemoryStoreIncr(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+
+	n, _ := s.IncrBy(ctx, "counter", 5)
+	assert.Equal(t, int64(5), n)
+	n, _ = s.IncrBy(ctx, "counter", -2)
+	assert.Equal(t, int64(3), n)
+}
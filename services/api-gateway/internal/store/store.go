@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotFound est retourné quand une clé n'existe pas (ou a expiré).
+var ErrNotFound = errors.New("key not found")
+
+// Store est le stockage clé/valeur partagé par les fonctionnalités du gateway.
+// Il est implémenté par Redis en production et en mémoire en local et en test.
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX écrit la clé seulement si elle n'existe pas et indique si l'écriture a eu lieu.
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, keys ...string) error
+	IncrBy(ctx context.Context, key string, n int64) (int64, error)
+	// Expire fixe la durée de vie d'une clé existante (compteurs créés par IncrBy).
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+
+	SAdd(ctx context.Context, key string, members ...string) error
+	SRem(ctx context.Context, key string, members ...string) error
+	SMembers(ctx context.Context, key string) ([]string, error)
+
+	// LPush ajoute en tête de liste et tronque la liste à max éléments (0 = illimité).
+	LPush(ctx context.Context, key, value string, max int) error
+	LRange(ctx context.Context, key string, start, stop int) ([]string, error)
+}
+
+// GetJSON lit une clé et décode sa valeur JSON dans v.
+func GetJSON(ctx context.Context, s Store, key string, v interface{}) error {
+	data, err := s.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), v)
+}
+
+// SetJSON encode v en JSON et l'écrit sous la clé donnée.
+func SetJSON(ctx context.Context, s Store, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.Set(ctx, key, string(data), ttl)
+}
+
+// RedisStore implémente Store avec Redis.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedis crée un store Redis à partir d'une URL redis:// ou d'une adresse host:port.
+func NewRedis(redisURL string) *RedisStore {
+	opts := &redis.Options{Addr: redisURL}
+	if strings.Contains(redisURL, "://") {
+		if parsed, err := redis.ParseURL(redisURL); err == nil {
+			opts = parsed
+		}
+	}
+	return &RedisStore{client: redis.NewClient(opts)}
+}
+
+// Client expose le client Redis sous-jacent.
+func (s *RedisStore) Client() *redis.Client {
+	return s.client
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	value, err := s.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (s *RedisStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *RedisStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	return s.client.Del(ctx, keys...).Err()
+}
+
+func (s *RedisStore) IncrBy(ctx context.Context, key string, n int64) (int64, error) {
+	return s.client.IncrBy(ctx, key, n).Result()
+}
+
+func (s *RedisStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.Expire(ctx, key, ttl).Err()
+}
+
+func (s *RedisStore) SAdd(ctx context.Context, key string, members ...string) error {
+	return s.client.SAdd(ctx, key, toInterfaces(members)...).Err()
+}
+
+func (s *RedisStore) SRem(ctx context.Context, key string, members ...string) error {
+	return s.client.SRem(ctx, key, toInterfaces(members)...).Err()
+}
+
+func (s *RedisStore) SMembers(ctx context.Context, key string) ([]string, error) {
+	return s.client.SMembers(ctx, key).Result()
+}
+
+func (s *RedisStore) LPush(ctx context.Context, key, value string, max int) error {
+	pipe := s.client.TxPipeline()
+	pipe.LPush(ctx, key, value)
+	if max > 0 {
+		pipe.LTrim(ctx, key, 0, int64(max-1))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) LRange(ctx context.Context, key string, start, stop int) ([]string, error) {
+	return s.client.LRange(ctx, key, int64(start), int64(stop)).Result()
+}
+
+func toInterfaces(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
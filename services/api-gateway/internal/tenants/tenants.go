@@ -0,0 +1,191 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// Tenant utilisé quand ni le service d'authentification ni le fournisseur OIDC n'en indiquent
+const Default = "default"
+
+// Rôles qu'un admin de tenant peut attribuer à ses membres (jamais "admin", global)
+var AssignableRoles = []string{"user", "readonly", "tenant_admin"}
+
+var (
+	// ErrNotFound est retourné quand l'utilisateur n'est pas membre du tenant.
+	ErrNotFound = errors.New("tenant member not found")
+	// ErrRemoved est retourné quand l'utilisateur a été retiré du tenant.
+	ErrRemoved = errors.New("tenant member removed")
+	// ErrRoleNotAssignable est retourné pour un rôle qu'un admin de tenant ne peut pas attribuer.
+	ErrRoleNotAssignable = errors.New("role cannot be assigned by a tenant admin")
+)
+
+// Member est l'appartenance d'un utilisateur à un tenant. Le rôle du membre fait
+// foi pour les tokens émis dans ce tenant : l'admin du tenant peut le modifier.
+// Le rôle admin, global, suit le fournisseur d'identité à chaque connexion.
+// Les identifiants utilisateur sont uniques entre tenants (émis par le service d'authentification).
+type Member struct {
+	TenantID  string     `json:"tenant_id"`
+	UserID    string     `json:"user_id"`
+	Username  string     `json:"username"`
+	Role      string     `json:"role"`
+	RemovedAt *time.Time `json:"removed_at,omitempty"`
+	JoinedAt  time.Time  `json:"joined_at"`
+}
+
+// Resolve retourne tenantID, ou le tenant par défaut s'il est vide.
+func Resolve(tenantID, fallback string) string {
+	if tenantID != "" {
+		return tenantID
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return Default
+}
+
+// Repository persiste les membres de chaque tenant dans le store.
+type Repository struct {
+	store store.Store
+}
+
+// NewRepository crée un repository adossé au store.
+func NewRepository(st store.Store) *Repository {
+	return &Repository{store: st}
+}
+
+func memberKey(tenantID, userID string) string {
+	return fmt.Sprintf("tenant:%s:member:%s", tenantID, userID)
+}
+
+func membersKey(tenantID string) string {
+	return fmt.Sprintf("tenant:%s:members", tenantID)
+}
+
+// Join enregistre l'utilisateur à sa première connexion et retourne son appartenance.
+// Un membre existant garde le rôle attribué dans le tenant ; un membre retiré est refusé.
+func (r *Repository) Join(ctx context.Context, tenantID, userID, username, role string) (*Member, error) {
+	member, err := r.Get(ctx, tenantID, userID)
+	if err == nil {
+		if member.RemovedAt != nil {
+			return nil, ErrRemoved
+		}
+		return member, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	member = &Member{
+		TenantID: tenantID,
+		UserID:   userID,
+		Username: username,
+		Role:     role,
+		JoinedAt: time.Now().UTC(),
+	}
+	if err := r.save(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, r.store.SAdd(ctx, membersKey(tenantID), userID)
+}
+
+// Get retourne l'appartenance d'un utilisateur au tenant.
+func (r *Repository) Get(ctx context.Context, tenantID, userID string) (*Member, error) {
+	var member Member
+	if err := store.GetJSON(ctx, r.store, memberKey(tenantID, userID), &member); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// List retourne les membres du tenant, y compris ceux retirés.
+func (r *Repository) List(ctx context.Context, tenantID string) ([]Member, error) {
+	ids, err := r.store.SMembers(ctx, membersKey(tenantID))
+	if err != nil {
+		return nil, err
+	}
+	members := make([]Member, 0, len(ids))
+	for _, id := range ids {
+		member, err := r.Get(ctx, tenantID, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *member)
+	}
+	return members, nil
+}
+
+// SetRole modifie le rôle d'un membre ; il s'applique aux prochains tokens.
+func (r *Repository) SetRole(ctx context.Context, tenantID, userID, role string) (*Member, error) {
+	if !assignable(role) {
+		return nil, ErrRoleNotAssignable
+	}
+	member, err := r.Get(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == "admin" {
+		// Un admin global n'est pas géré par les admins de tenant
+		return nil, ErrRoleNotAssignable
+	}
+	member.Role = role
+	return member, r.save(ctx, member)
+}
+
+// AssignRole attribue n'importe quel rôle, y compris admin, à un membre. Réservé aux
+// admins globaux ; les admins de tenant passent par SetRole.
+func (r *Repository) AssignRole(ctx context.Context, tenantID, userID, role string) (*Member, error) {
+	member, err := r.Get(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	member.Role = role
+	return member, r.save(ctx, member)
+}
+
+// Remove retire un membre du tenant. Ses connexions suivantes sont refusées.
+func (r *Repository) Remove(ctx context.Context, tenantID, userID string) (*Member, error) {
+	member, err := r.Get(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == "admin" {
+		return nil, ErrRoleNotAssignable
+	}
+	now := time.Now().UTC()
+	member.RemovedAt = &now
+	return member, r.save(ctx, member)
+}
+
+// Restore réintègre un membre retiré.
+func (r *Repository) Restore(ctx context.Context, tenantID, userID string) (*Member, error) {
+	member, err := r.Get(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	member.RemovedAt = nil
+	return member, r.save(ctx, member)
+}
+
+func (r *Repository) save(ctx context.Context, member *Member) error {
+	return store.SetJSON(ctx, r.store, memberKey(member.TenantID, member.UserID), member, 0)
+}
+
+func assignable(role string) bool {
+	for _, r := range AssignableRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package tokens
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// Usages des tokens d'action
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+var (
+	// ErrInvalidActionToken est retourné pour un token mal formé, mal signé, expiré
+	// ou émis pour un autre usage.
+	ErrInvalidActionToken = errors.New("invalid action token")
+	// ErrActionTokenUsed est retourné quand le token a déjà été utilisé.
+	ErrActionTokenUsed = errors.New("action token already used")
+)
+
+// ActionClaims est le contenu signé d'un token d'action.
+type ActionClaims struct {
+	Purpose   string    `json:"purpose"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email,omitempty"`
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ActionTokens émet des tokens signés (HMAC-SHA256), à usage unique et à durée
+// limitée, envoyés par email : vérification d'adresse, réinitialisation du mot de passe.
+// Le contenu est lisible par le destinataire ; il ne doit pas contenir de secret.
+type ActionTokens struct {
+	store store.Store
+	key   []byte
+}
+
+// NewActionTokens crée un émetteur de tokens d'action. La clé de signature est
+// dérivée du secret pour ne pas réutiliser telle quelle la clé des JWT.
+func NewActionTokens(st store.Store, secret string) *ActionTokens {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("action-tokens"))
+	return &ActionTokens{store: st, key: mac.Sum(nil)}
+}
+
+func actionUsedKey(nonce string) string {
+	return fmt.Sprintf("action_token_used:%s", nonce)
+}
+
+// Issue signe un token pour l'usage donné, valable ttl.
+func (a *ActionTokens) Issue(purpose, userID, email string, ttl time.Duration) (string, error) {
+	nonce, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(ActionClaims{
+		Purpose:   purpose,
+		UserID:    userID,
+		Email:     email,
+		Nonce:     nonce,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + a.sign(encoded), nil
+}
+
+// Consume vérifie le token et le marque comme utilisé. Un second appel avec le
+// même token retourne ErrActionTokenUsed.
+func (a *ActionTokens) Consume(ctx context.Context, purpose, token string) (*ActionClaims, error) {
+	claims, err := a.Verify(purpose, token)
+	if err != nil {
+		return nil, err
+	}
+	// Conservé jusqu'à l'expiration du token, après quoi la signature suffit à le refuser
+	ttl := time.Until(claims.ExpiresAt) + time.Second
+	first, err := a.store.SetNX(ctx, actionUsedKey(claims.Nonce), "1", ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		return nil, ErrActionTokenUsed
+	}
+	return claims, nil
+}
+
+// Release rend utilisable un token consommé, quand l'action qu'il autorisait a échoué.
+func (a *ActionTokens) Release(ctx context.Context, claims *ActionClaims) error {
+	return a.store.Delete(ctx, actionUsedKey(claims.Nonce))
+}
+
+// Verify vérifie la signature, l'usage et l'expiration sans consommer le token.
+func (a *ActionTokens) Verify(purpose, token string) (*ActionClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(a.sign(encoded))) {
+		return nil, ErrInvalidActionToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	var claims ActionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	if claims.Purpose != purpose || time.Now().After(claims.ExpiresAt) {
+		return nil, ErrInvalidActionToken
+	}
+	return &claims, nil
+}
+
+func (a *ActionTokens) sign(encoded string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
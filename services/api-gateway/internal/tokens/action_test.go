@@ -0,0 +1,52 @@
+package tokens
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionTokenSingleUse(t *testing.T) {
+	ctx := context.Background()
+	actions := NewActionTokens(store.NewMemory(), "secret")
+
+	token, err := actions.Issue(PurposeResetPassword, "123", "alice@example.com", time.Hour)
+	assert.NoError(t, err)
+
+	claims, err := actions.Consume(ctx, PurposeResetPassword, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "123", claims.UserID)
+	assert.Equal(t, "alice@example.com", claims.Email)
+
+	_, err = actions.Consume(ctx, PurposeResetPassword, token)
+	assert.ErrorIs(t, err, ErrActionTokenUsed)
+}
+
+func TestActionTokenRejected(t *testing.T) {
+	ctx := context.Background()
+	actions := NewActionTokens(store.NewMemory(), "secret")
+
+	// Autre usage
+	token, _ := actions.Issue(PurposeVerifyEmail, "123", "alice@example.com", time.Hour)
+	_, err := actions.Consume(ctx, PurposeResetPassword, token)
+	assert.ErrorIs(t, err, ErrInvalidActionToken)
+
+	// Signature d'une autre clé
+	other, _ := NewActionTokens(store.NewMemory(), "other").Issue(PurposeVerifyEmail, "123", "", time.Hour)
+	_, err = actions.Consume(ctx, PurposeVerifyEmail, other)
+	assert.ErrorIs(t, err, ErrInvalidActionToken)
+
+	// Contenu modifié
+	payload, signature, _ := strings.Cut(token, ".")
+	_, err = actions.Consume(ctx, PurposeVerifyEmail, payload+"x."+signature)
+	assert.ErrorIs(t, err, ErrInvalidActionToken)
+
+	// Expiré
+	expired, _ := actions.Issue(PurposeVerifyEmail, "123", "", -time.Second)
+	_, err = actions.Consume(ctx, PurposeVerifyEmail, expired)
+	assert.ErrorIs(t, err, ErrInvalidActionToken)
+}
@@ -0,0 +1,113 @@
+package tokens
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func init() {
+	// exp, nbf et iat à la milliseconde : la Denylist distingue ainsi un token émis
+	// juste après une révocation (reconnexion immédiate) de ceux qu'elle révoque
+	jwt.TimePrecision = time.Millisecond
+}
+
+// Claims sont les claims des tokens d'accès, utilisés à l'émission comme à la
+// vérification. Un claim du mauvais type (ex. user_id numérique) rend le token invalide.
+type Claims struct {
+	UserID    string   `json:"user_id"`
+	Username  string   `json:"username"`
+	Role      string   `json:"role"`
+	TenantID  string   `json:"tenant_id"`
+	SessionID string   `json:"sid,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+	// Actor est l'admin qui agit au nom de l'utilisateur (impersonation)
+	Actor *Actor `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ClaimsConfig fixe l'émetteur (iss) et l'audience (aud) des tokens d'accès, et la
+// tolérance d'horloge appliquée à exp et nbf. Un émetteur ou une audience vide
+// n'est ni ajouté aux tokens ni vérifié.
+type ClaimsConfig struct {
+	Issuer   string
+	Audience []string
+	Leeway   time.Duration
+	// LegacyUntil accepte, pendant la migration, les tokens émis sans iss ni aud
+	// dont l'expiration ne dépasse pas cette date ; zéro pour les refuser.
+	LegacyUntil time.Time
+}
+
+// NewClaims construit les claims d'un token d'accès valable ttl à partir de maintenant.
+func NewClaims(subject Subject, jti string, ttl time.Duration, cfg ClaimsConfig) *Claims {
+	now := time.Now()
+	claims := &Claims{
+		UserID:    subject.UserID,
+		Username:  subject.Username,
+		Role:      subject.Role,
+		TenantID:  subject.TenantID,
+		SessionID: subject.SessionID,
+		Actor:     subject.Actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   subject.UserID,
+			Issuer:    cfg.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	if len(cfg.Audience) > 0 {
+		claims.Audience = jwt.ClaimStrings(cfg.Audience)
+	}
+	return claims
+}
+
+// ParseClaims vérifie la signature (clé et algorithme du KeySet), puis exp, nbf, iss
+// et aud selon cfg.
+func ParseClaims(tokenString string, keys *KeySet, cfg ClaimsConfig) (*Claims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(keys.ValidMethods()),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.Keyfunc, options...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	if claims.isLegacy(cfg.LegacyUntil) {
+		return claims, nil
+	}
+	if cfg.Issuer != "" && claims.Issuer != cfg.Issuer {
+		return nil, errors.Join(jwt.ErrTokenInvalidClaims, jwt.ErrTokenInvalidIssuer)
+	}
+	// jwt.WithAudience n'accepte qu'une valeur : le token doit viser l'une des audiences
+	if len(cfg.Audience) > 0 && !claims.hasAudience(cfg.Audience) {
+		return nil, errors.Join(jwt.ErrTokenInvalidClaims, jwt.ErrTokenInvalidAudience)
+	}
+	return claims, nil
+}
+
+// isLegacy indique un token émis avant l'ajout de iss et aud, encore accepté :
+// l'expiration bornée par until empêche d'en forger après la migration.
+func (c *Claims) isLegacy(until time.Time) bool {
+	if until.IsZero() || c.Issuer != "" || len(c.Audience) > 0 || c.ExpiresAt == nil {
+		return false
+	}
+	return !c.ExpiresAt.After(until)
+}
+
+func (c *Claims) hasAudience(accepted []string) bool {
+	for _, aud := range c.Audience {
+		for _, expected := range accepted {
+			if aud == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package tokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimsRoundTrip(t *testing.T) {
+	ks := NewHMACKeySet("secret")
+	cfg := ClaimsConfig{Issuer: "gateway", Audience: []string{"api"}, Leeway: 5 * time.Second}
+
+	subject := Subject{UserID: "123", Username: "alice", Role: "user", TenantID: "acme", SessionID: "s1", Actor: &Actor{UserID: "admin-1", Username: "root"}}
+	tokenString, err := ks.Sign(NewClaims(subject, "jti-1", time.Minute, cfg))
+	require.NoError(t, err)
+
+	claims, err := ParseClaims(tokenString, ks, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "123", claims.UserID)
+	assert.Equal(t, "123", claims.Subject)
+	assert.Equal(t, "acme", claims.TenantID)
+	assert.Equal(t, "s1", claims.SessionID)
+	assert.Equal(t, "jti-1", claims.ID)
+	assert.Equal(t, "root", claims.Actor.Username)
+
+	// Émetteur ou audience inattendus
+	_, err = ParseClaims(tokenString, ks, ClaimsConfig{Issuer: "other"})
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+	_, err = ParseClaims(tokenString, ks, ClaimsConfig{Audience: []string{"billing"}})
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+	// Une des audiences acceptées suffit
+	_, err = ParseClaims(tokenString, ks, ClaimsConfig{Audience: []string{"billing", "api"}})
+	assert.NoError(t, err)
+}
+
+func TestParseClaimsValidation(t *testing.T) {
+	ks := NewHMACKeySet("secret")
+	sign := func(claims jwt.MapClaims) string {
+		tokenString, _ := ks.Sign(claims)
+		return tokenString
+	}
+	now := time.Now()
+
+	// nbf légèrement dans le futur : accepté seulement avec une tolérance d'horloge
+	skewed := sign(jwt.MapClaims{"user_id": "123", "nbf": now.Add(3 * time.Second).Unix(), "exp": now.Add(time.Minute).Unix()})
+	_, err := ParseClaims(skewed, ks, ClaimsConfig{})
+	assert.ErrorIs(t, err, jwt.ErrTokenNotValidYet)
+	_, err = ParseClaims(skewed, ks, ClaimsConfig{Leeway: 10 * time.Second})
+	assert.NoError(t, err)
+
+	// Token expiré depuis peu : idem
+	expired := sign(jwt.MapClaims{"user_id": "123", "exp": now.Add(-3 * time.Second).Unix()})
+	_, err = ParseClaims(expired, ks, ClaimsConfig{})
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	_, err = ParseClaims(expired, ks, ClaimsConfig{Leeway: 10 * time.Second})
+	assert.NoError(t, err)
+
+	// Un user_id numérique n'est pas converti silencieusement
+	_, err = ParseClaims(sign(jwt.MapClaims{"user_id": 123, "exp": now.Add(time.Minute).Unix()}), ks, ClaimsConfig{})
+	assert.ErrorIs(t, err, jwt.ErrTokenMalformed)
+}
+
+func TestParseClaimsLegacyTokens(t *testing.T) {
+	ks := NewHMACKeySet("secret")
+	cfg := ClaimsConfig{Issuer: "gateway", Audience: []string{"api"}}
+	now := time.Now()
+	legacy, _ := ks.Sign(jwt.MapClaims{"user_id": "123", "exp": now.Add(time.Minute).Unix()})
+
+	// Sans fenêtre de migration, un token sans iss ni aud est refusé
+	_, err := ParseClaims(legacy, ks, cfg)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	// Accepté tant que son expiration reste dans la fenêtre
+	cfg.LegacyUntil = now.Add(5 * time.Minute)
+	claims, err := ParseClaims(legacy, ks, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "123", claims.UserID)
+
+	// Une expiration au-delà de la fenêtre trahit un token forgé après la migration
+	late, _ := ks.Sign(jwt.MapClaims{"user_id": "123", "exp": now.Add(time.Hour).Unix()})
+	_, err = ParseClaims(late, ks, cfg)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	// Un émetteur présent mais différent reste refusé
+	other, _ := ks.Sign(jwt.MapClaims{"user_id": "123", "iss": "other", "exp": now.Add(time.Minute).Unix()})
+	_, err = ParseClaims(other, ks, cfg)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+}
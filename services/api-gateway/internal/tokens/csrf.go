@@ -0,0 +1,35 @@
+package tokens
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// NewCSRFToken génère un token CSRF « double submit » signé, lié à l'utilisateur :
+// un cookie posé par un sous-domaine ne peut pas être réutilisé pour un autre compte.
+func NewCSRFToken(secret, userID string) (string, error) {
+	nonce, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+	return nonce + "." + csrfSignature(secret, userID, nonce), nil
+}
+
+// VerifyCSRFToken vérifie la signature d'un token CSRF pour l'utilisateur.
+func VerifyCSRFToken(secret, userID, token string) bool {
+	nonce, signature, ok := strings.Cut(token, ".")
+	if !ok || nonce == "" {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(csrfSignature(secret, userID, nonce)))
+}
+
+func csrfSignature(secret, userID, nonce string) string {
+	key := hmac.New(sha256.New, []byte(secret))
+	key.Write([]byte("csrf"))
+	mac := hmac.New(sha256.New, key.Sum(nil))
+	mac.Write([]byte(userID + "." + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
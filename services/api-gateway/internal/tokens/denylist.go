@@ -0,0 +1,169 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// Denylist révoque des tokens d'accès avant leur expiration, soit individuellement
+// (par jti), soit pour tous les tokens d'un utilisateur émis avant une date.
+// Les réponses du store sont mises en cache localement pendant cacheTTL : une
+// révocation faite sur un autre réplica prend effet au plus tard après ce délai.
+type Denylist struct {
+	store    store.Store
+	maxTTL   time.Duration
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedValue
+}
+
+type cachedValue struct {
+	value     int64 // 0 = absent ; sinon 1 pour un jti, date de révocation (ms) pour un utilisateur
+	expiresAt time.Time
+}
+
+// NewDenylist crée une denylist. maxTTL est la durée de vie maximale d'un token
+// d'accès : au-delà, une révocation n'a plus besoin d'être conservée.
+func NewDenylist(st store.Store, maxTTL, cacheTTL time.Duration) *Denylist {
+	return &Denylist{
+		store:    st,
+		maxTTL:   maxTTL,
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]cachedValue),
+	}
+}
+
+func denylistJTIKey(jti string) string {
+	return fmt.Sprintf("denylist:jti:%s", jti)
+}
+
+func denylistSessionKey(sessionID string) string {
+	return fmt.Sprintf("denylist:sid:%s", sessionID)
+}
+
+func denylistUserKey(userID string) string {
+	return fmt.Sprintf("denylist:user:%s", userID)
+}
+
+// RevokeToken révoque un token jusqu'à son expiration.
+func (d *Denylist) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	key := denylistJTIKey(jti)
+	if err := d.store.Set(ctx, key, "1", ttl); err != nil {
+		return err
+	}
+	d.remember(key, 1)
+	return nil
+}
+
+// RevokeUser révoque tous les tokens de l'utilisateur émis jusqu'à maintenant.
+// La date est conservée à la milliseconde, comme l'iat des tokens.
+func (d *Denylist) RevokeUser(ctx context.Context, userID string) error {
+	now := time.Now().UnixMilli()
+	key := denylistUserKey(userID)
+	if err := d.store.Set(ctx, key, strconv.FormatInt(now, 10), d.maxTTL); err != nil {
+		return err
+	}
+	d.remember(key, now)
+	return nil
+}
+
+// RevokeSession révoque tous les tokens d'accès d'une session (claim "sid").
+func (d *Denylist) RevokeSession(ctx context.Context, sessionID string) error {
+	key := denylistSessionKey(sessionID)
+	if err := d.store.Set(ctx, key, "1", d.maxTTL); err != nil {
+		return err
+	}
+	d.remember(key, 1)
+	return nil
+}
+
+// IsSessionRevoked indique si la session du token a été révoquée.
+func (d *Denylist) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+	revoked, err := d.lookup(ctx, denylistSessionKey(sessionID))
+	return revoked != 0, err
+}
+
+// IsRevoked indique si le token (jti, utilisateur, date d'émission) a été révoqué.
+func (d *Denylist) IsRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		revoked, err := d.lookup(ctx, denylistJTIKey(jti))
+		if err != nil {
+			return false, err
+		}
+		if revoked != 0 {
+			return true, nil
+		}
+	}
+
+	revokedAt, err := d.lookup(ctx, denylistUserKey(userID))
+	if err != nil {
+		return false, err
+	}
+	// Révocation enregistrée en secondes par une version précédente
+	if revokedAt != 0 && revokedAt < legacySecondsLimit {
+		revokedAt *= 1000
+	}
+	return revokedAt != 0 && issuedAt.UnixMilli() <= revokedAt, nil
+}
+
+// legacySecondsLimit sépare une date en secondes (~1.7e9) d'une date en millisecondes (~1.7e12).
+const legacySecondsLimit = 100_000_000_000
+
+// lookup lit une clé en passant par le cache local.
+func (d *Denylist) lookup(ctx context.Context, key string) (int64, error) {
+	d.mu.Lock()
+	cached, ok := d.cache[key]
+	d.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	var value int64
+	raw, err := d.store.Get(ctx, key)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+	case err != nil:
+		return 0, err
+	default:
+		value, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	d.remember(key, value)
+	return value, nil
+}
+
+func (d *Denylist) remember(key string, value int64) {
+	if d.cacheTTL <= 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Purger les entrées expirées pour borner la taille du cache
+	now := time.Now()
+	if len(d.cache) > 10000 {
+		for k, v := range d.cache {
+			if now.After(v.expiresAt) {
+				delete(d.cache, k)
+			}
+		}
+	}
+	d.cache[key] = cachedValue{value: value, expiresAt: now.Add(d.cacheTTL)}
+}
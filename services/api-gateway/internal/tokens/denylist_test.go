@@ -0,0 +1,46 @@
+package tokens
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDenylistRevokeUserPrecision(t *testing.T) {
+	ctx := context.Background()
+	denylist := NewDenylist(store.NewMemory(), time.Hour, 0)
+	ks := NewHMACKeySet("secret")
+
+	before, _ := ks.Sign(NewClaims(Subject{UserID: "123"}, "before", time.Minute, ClaimsConfig{}))
+	time.Sleep(2 * time.Millisecond)
+	require.NoError(t, denylist.RevokeUser(ctx, "123"))
+	time.Sleep(2 * time.Millisecond)
+	// Reconnexion dans la même seconde que la révocation
+	after, _ := ks.Sign(NewClaims(Subject{UserID: "123"}, "after", time.Minute, ClaimsConfig{}))
+
+	for token, want := range map[string]bool{before: true, after: false} {
+		claims, err := ParseClaims(token, ks, ClaimsConfig{})
+		require.NoError(t, err)
+		revoked, err := denylist.IsRevoked(ctx, claims.ID, claims.UserID, claims.IssuedAt.Time)
+		require.NoError(t, err)
+		assert.Equal(t, want, revoked, claims.ID)
+	}
+}
+
+func TestDenylistLegacySecondsRevocation(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	denylist := NewDenylist(st, time.Hour, 0)
+	revokedAt := time.Now().Add(-time.Minute)
+	require.NoError(t, st.Set(ctx, denylistUserKey("456"), strconv.FormatInt(revokedAt.Unix(), 10), time.Hour))
+
+	revoked, _ := denylist.IsRevoked(ctx, "", "456", revokedAt.Add(-time.Second))
+	assert.True(t, revoked)
+	revoked, _ = denylist.IsRevoked(ctx, "", "456", revokedAt.Add(2*time.Second))
+	assert.False(t, revoked)
+}
@@ -0,0 +1,290 @@
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnknownKey est retourné quand le kid d'un token ne correspond à aucune clé.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key est une clé de signature (ou de vérification seule si Private est nil).
+type Key struct {
+	ID         string
+	Algorithm  string
+	Private    crypto.Signer
+	Public     crypto.PublicKey
+	ActiveFrom time.Time
+	path       string
+}
+
+// KeySpec décrit une clé à charger : "kid=chemin.pem" ou "kid=chemin.pem@2026-01-01T00:00:00Z".
+// La date optionnelle est le moment où la clé devient la clé de signature.
+type KeySpec struct {
+	ID         string
+	Path       string
+	ActiveFrom time.Time
+}
+
+// ParseKeySpec lit une spécification de clé au format "kid=chemin[@date RFC3339]".
+func ParseKeySpec(spec string) (KeySpec, error) {
+	kid, rest, ok := strings.Cut(strings.TrimSpace(spec), "=")
+	if !ok || kid == "" || rest == "" {
+		return KeySpec{}, fmt.Errorf("invalid key spec %q, expected kid=path[@activation]", spec)
+	}
+	ks := KeySpec{ID: kid, Path: rest}
+	if path, activation, ok := strings.Cut(rest, "@"); ok {
+		t, err := time.Parse(time.RFC3339, activation)
+		if err != nil {
+			return KeySpec{}, fmt.Errorf("invalid activation time in key spec %q: %v", spec, err)
+		}
+		ks.Path = path
+		ks.ActiveFrom = t
+	}
+	return ks, nil
+}
+
+// KeySet regroupe les clés de signature et de vérification des tokens.
+// La clé de signature est la clé privée la plus récemment activée ; toutes les
+// clés configurées restent acceptées en vérification. HS256 (secret partagé)
+// n'est accepté que pendant la fenêtre de migration, ou s'il n'y a aucune clé
+// asymétrique.
+type KeySet struct {
+	mu         sync.RWMutex
+	keys       []*Key
+	hmacSecret []byte
+	hmacUntil  time.Time
+}
+
+// NewHMACKeySet crée un KeySet qui signe et vérifie en HS256 avec un secret partagé.
+func NewHMACKeySet(secret string) *KeySet {
+	return &KeySet{hmacSecret: []byte(secret)}
+}
+
+// LoadKeySet charge les clés PEM décrites par specs. hmacUntil borne la période
+// pendant laquelle les anciens tokens HS256 signés avec hmacSecret sont acceptés.
+func LoadKeySet(specs []KeySpec, hmacSecret string, hmacUntil time.Time) (*KeySet, error) {
+	ks := &KeySet{hmacSecret: []byte(hmacSecret), hmacUntil: hmacUntil}
+	keys, err := loadKeys(specs)
+	if err != nil {
+		return nil, err
+	}
+	ks.keys = keys
+	return ks, nil
+}
+
+// Reload relit les fichiers de clés, pour prendre en compte une rotation sans redémarrage.
+func (ks *KeySet) Reload() error {
+	ks.mu.RLock()
+	specs := make([]KeySpec, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		specs = append(specs, KeySpec{ID: k.ID, Path: k.path, ActiveFrom: k.ActiveFrom})
+	}
+	ks.mu.RUnlock()
+
+	keys, err := loadKeys(specs)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+	return nil
+}
+
+func loadKeys(specs []KeySpec) ([]*Key, error) {
+	keys := make([]*Key, 0, len(specs))
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		if seen[spec.ID] {
+			return nil, fmt.Errorf("duplicate key id %q", spec.ID)
+		}
+		seen[spec.ID] = true
+
+		data, err := os.ReadFile(spec.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key %s: %v", spec.ID, err)
+		}
+		key, err := ParsePEMKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key %s: %v", spec.ID, err)
+		}
+		key.ID = spec.ID
+		key.ActiveFrom = spec.ActiveFrom
+		key.path = spec.Path
+		keys = append(keys, key)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].ActiveFrom.Before(keys[j].ActiveFrom)
+	})
+	return keys, nil
+}
+
+// ParsePEMKey lit une clé privée (PKCS#8 ou PKCS#1) ou publique (PKIX) RSA ou Ed25519.
+func ParsePEMKey(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{Algorithm: jwt.SigningMethodRS256.Alg(), Private: k, Public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{Algorithm: jwt.SigningMethodEdDSA.Alg(), Private: k, Public: k.Public()}, nil
+	case *rsa.PublicKey:
+		return &Key{Algorithm: jwt.SigningMethodRS256.Alg(), Public: k}, nil
+	case ed25519.PublicKey:
+		return &Key{Algorithm: jwt.SigningMethodEdDSA.Alg(), Public: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
+
+// signingKey retourne la clé privée active la plus récente, ou nil.
+func (ks *KeySet) signingKey(now time.Time) *Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	var current *Key
+	for _, k := range ks.keys {
+		if k.Private != nil && !k.ActiveFrom.After(now) {
+			current = k
+		}
+	}
+	return current
+}
+
+func (ks *KeySet) hasAsymmetricKeys() bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return len(ks.keys) > 0
+}
+
+// hmacAccepted indique si les tokens HS256 sont encore acceptés.
+func (ks *KeySet) hmacAccepted(now time.Time) bool {
+	if len(ks.hmacSecret) == 0 {
+		return false
+	}
+	if !ks.hasAsymmetricKeys() {
+		return true
+	}
+	return now.Before(ks.hmacUntil)
+}
+
+// Sign signe les claims avec la clé active. Sans clé asymétrique, on signe en HS256.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key := ks.signingKey(time.Now())
+	if key == nil {
+		if ks.hasAsymmetricKeys() || len(ks.hmacSecret) == 0 {
+			return "", errors.New("no active signing key")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.hmacSecret)
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc retourne la clé de vérification d'un token, à utiliser avec jwt.Parse.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() || !ks.hmacAccepted(time.Now()) {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return ks.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for _, k := range ks.keys {
+		if k.ID != kid {
+			continue
+		}
+		if k.Algorithm != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return k.Public, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// ValidMethods liste les algorithmes acceptés, pour jwt.WithValidMethods.
+func (ks *KeySet) ValidMethods() []string {
+	return []string{
+		jwt.SigningMethodHS256.Alg(),
+		jwt.SigningMethodRS256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+	}
+}
+
+// JWK est une clé publique au format JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS retourne les clés publiques de vérification. Les clés dont l'activation
+// est planifiée sont publiées à l'avance pour que les services les connaissent.
+func (ks *KeySet) JWKS() []JWK {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	jwks := make([]JWK, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+		switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
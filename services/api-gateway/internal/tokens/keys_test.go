@@ -0,0 +1,117 @@
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePEMKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+func parseWith(ks *KeySet, tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, ks.Keyfunc, jwt.WithValidMethods(ks.ValidMethods()))
+}
+
+func TestKeySetSignsWithActiveKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	ks, err := LoadKeySet([]KeySpec{
+		{ID: "rsa-1", Path: writePEMKey(t, rsaKey)},
+		{ID: "ed-2", Path: writePEMKey(t, edKey), ActiveFrom: time.Now().Add(time.Hour)},
+	}, "", time.Time{})
+	assert.NoError(t, err)
+
+	// La clé Ed25519 n'est pas encore active : on signe avec la clé RSA
+	tokenString, err := ks.Sign(jwt.MapClaims{"sub": "123"})
+	assert.NoError(t, err)
+
+	token, err := parseWith(ks, tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, "rsa-1", token.Header["kid"])
+	assert.Equal(t, "RS256", token.Method.Alg())
+
+	// Les deux clés sont publiées
+	jwks := ks.JWKS()
+	assert.Len(t, jwks, 2)
+	assert.Equal(t, "RSA", jwks[0].Kty)
+	assert.Equal(t, "OKP", jwks[1].Kty)
+}
+
+func TestKeySetRotation(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	ks, err := LoadKeySet([]KeySpec{
+		{ID: "rsa-1", Path: writePEMKey(t, rsaKey), ActiveFrom: time.Now().Add(-48 * time.Hour)},
+		{ID: "ed-2", Path: writePEMKey(t, edKey), ActiveFrom: time.Now().Add(-time.Hour)},
+	}, "", time.Time{})
+	assert.NoError(t, err)
+
+	tokenString, _ := ks.Sign(jwt.MapClaims{"sub": "123"})
+	token, err := parseWith(ks, tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, "ed-2", token.Header["kid"])
+	assert.Equal(t, "EdDSA", token.Method.Alg())
+
+	// Un token signé par l'ancienne clé reste vérifiable
+	old := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "123"})
+	old.Header["kid"] = "rsa-1"
+	oldString, _ := old.SignedString(rsaKey)
+	_, err = parseWith(ks, oldString)
+	assert.NoError(t, err)
+}
+
+func TestKeySetHS256MigrationWindow(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	path := writePEMKey(t, edKey)
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "123"}).SignedString([]byte("secret"))
+
+	during, _ := LoadKeySet([]KeySpec{{ID: "ed-1", Path: path}}, "secret", time.Now().Add(time.Hour))
+	_, err := parseWith(during, legacy)
+	assert.NoError(t, err)
+
+	after, _ := LoadKeySet([]KeySpec{{ID: "ed-1", Path: path}}, "secret", time.Now().Add(-time.Hour))
+	_, err = parseWith(after, legacy)
+	assert.Error(t, err)
+}
+
+func TestKeySetRejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ks, _ := LoadKeySet([]KeySpec{{ID: "rsa-1", Path: writePEMKey(t, rsaKey)}}, "", time.Time{})
+
+	// Token EdDSA présentant le kid d'une clé RSA
+	forged := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{"sub": "123"})
+	forged.Header["kid"] = "rsa-1"
+	forgedString, _ := forged.SignedString(edKey)
+
+	_, err := parseWith(ks, forgedString)
+	assert.Error(t, err)
+}
+
+func TestParseKeySpec(t *testing.T) {
+	spec, err := ParseKeySpec("2026-q4=/etc/keys/q4.pem@2026-10-01T00:00:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-q4", spec.ID)
+	assert.Equal(t, "/etc/keys/q4.pem", spec.Path)
+	assert.Equal(t, 2026, spec.ActiveFrom.Year())
+
+	_, err = ParseKeySpec("missing-path")
+	assert.Error(t, err)
+}
@@ -0,0 +1,213 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+var (
+	// ErrInvalidRefreshToken est retourné pour un refresh token inconnu, expiré ou révoqué.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused est retourné quand un refresh token déjà utilisé est présenté :
+	// toute la famille est alors révoquée.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// Subject identifie l'utilisateur à qui les tokens sont délivrés.
+type Subject struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	TenantID string `json:"tenant_id"`
+	// SessionID identifie la session (famille de refresh tokens), claim "sid" des tokens d'accès
+	SessionID string `json:"session_id,omitempty"`
+	// Actor est l'admin qui agit au nom de l'utilisateur (impersonation)
+	Actor *Actor `json:"actor,omitempty"`
+}
+
+// Actor est porté par le claim "act" (RFC 8693) des tokens d'impersonation.
+type Actor struct {
+	UserID   string `json:"sub"`
+	Username string `json:"username"`
+}
+
+// refreshRecord est l'état d'un refresh token, stocké sous le hash du token.
+type refreshRecord struct {
+	Subject
+	FamilyID  string    `json:"family_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RefreshStore gère des refresh tokens opaques, à usage unique, regroupés en familles.
+// Chaque rotation reste dans la même famille ; la réutilisation d'un ancien token
+// révoque la famille entière.
+type RefreshStore struct {
+	store store.Store
+	ttl   time.Duration
+}
+
+// NewRefreshStore crée un RefreshStore dont les tokens expirent après ttl.
+func NewRefreshStore(st store.Store, ttl time.Duration) *RefreshStore {
+	return &RefreshStore{store: st, ttl: ttl}
+}
+
+func refreshKey(hash string) string {
+	return fmt.Sprintf("refresh:%s", hash)
+}
+
+func refreshUsedKey(hash string) string {
+	return fmt.Sprintf("refresh_used:%s", hash)
+}
+
+func familyRevokedKey(familyID string) string {
+	return fmt.Sprintf("refresh_family_revoked:%s", familyID)
+}
+
+func userFamiliesKey(userID string) string {
+	return fmt.Sprintf("refresh_families:user:%s", userID)
+}
+
+// Issue démarre une nouvelle famille et retourne son premier refresh token.
+// La famille reprend l'identifiant de session du sujet s'il est renseigné.
+func (r *RefreshStore) Issue(ctx context.Context, subject Subject) (string, error) {
+	familyID := subject.SessionID
+	if familyID == "" {
+		var err error
+		familyID, err = randomString(16)
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := r.store.SAdd(ctx, userFamiliesKey(subject.UserID), familyID); err != nil {
+		return "", err
+	}
+	return r.issueInFamily(ctx, subject, familyID)
+}
+
+func (r *RefreshStore) issueInFamily(ctx context.Context, subject Subject, familyID string) (string, error) {
+	token, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	record := refreshRecord{
+		Subject:   subject,
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(r.ttl).UTC(),
+	}
+	if err := store.SetJSON(ctx, r.store, refreshKey(hashToken(token)), record, r.ttl); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Rotate consomme un refresh token et en émet un nouveau dans la même famille.
+func (r *RefreshStore) Rotate(ctx context.Context, token string) (string, *Subject, error) {
+	hash := hashToken(token)
+
+	var record refreshRecord
+	if err := store.GetJSON(ctx, r.store, refreshKey(hash), &record); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return "", nil, ErrInvalidRefreshToken
+		}
+		return "", nil, err
+	}
+
+	revoked, err := r.familyRevoked(ctx, record.FamilyID)
+	if err != nil {
+		return "", nil, err
+	}
+	if revoked {
+		return "", nil, ErrInvalidRefreshToken
+	}
+
+	// Marquage atomique : un seul appel peut consommer le token
+	first, err := r.store.SetNX(ctx, refreshUsedKey(hash), "1", time.Until(record.ExpiresAt))
+	if err != nil {
+		return "", nil, err
+	}
+	if !first {
+		if err := r.RevokeFamily(ctx, record.FamilyID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, ErrRefreshTokenReused
+	}
+
+	newToken, err := r.issueInFamily(ctx, record.Subject, record.FamilyID)
+	if err != nil {
+		return "", nil, err
+	}
+	return newToken, &record.Subject, nil
+}
+
+// Lookup retourne le sujet d'un refresh token sans le consommer.
+func (r *RefreshStore) Lookup(ctx context.Context, token string) (*Subject, error) {
+	var record refreshRecord
+	if err := store.GetJSON(ctx, r.store, refreshKey(hashToken(token)), &record); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	return &record.Subject, nil
+}
+
+// Revoke invalide la famille du refresh token donné (déconnexion).
+func (r *RefreshStore) Revoke(ctx context.Context, token string) error {
+	var record refreshRecord
+	if err := store.GetJSON(ctx, r.store, refreshKey(hashToken(token)), &record); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	return r.RevokeFamily(ctx, record.FamilyID)
+}
+
+// RevokeFamily invalide tous les refresh tokens d'une famille.
+func (r *RefreshStore) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.store.Set(ctx, familyRevokedKey(familyID), "1", r.ttl)
+}
+
+// RevokeUser invalide toutes les familles de refresh tokens d'un utilisateur.
+func (r *RefreshStore) RevokeUser(ctx context.Context, userID string) error {
+	families, err := r.store.SMembers(ctx, userFamiliesKey(userID))
+	if err != nil {
+		return err
+	}
+	for _, familyID := range families {
+		if err := r.RevokeFamily(ctx, familyID); err != nil {
+			return err
+		}
+	}
+	return r.store.Delete(ctx, userFamiliesKey(userID))
+}
+
+func (r *RefreshStore) familyRevoked(ctx context.Context, familyID string) (bool, error) {
+	_, err := r.store.Get(ctx, familyRevokedKey(familyID))
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// hashToken évite de stocker les tokens en clair.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,66 @@
+package tokens
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	refreshTokens := NewRefreshStore(store.NewMemory(), time.Hour)
+
+	first, err := refreshTokens.Issue(ctx, Subject{UserID: "123", Username: "testuser", Role: "user"})
+	assert.NoError(t, err)
+
+	second, subject, err := refreshTokens.Rotate(ctx, first)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, "testuser", subject.Username)
+
+	third, _, err := refreshTokens.Rotate(ctx, second)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, third)
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	refreshTokens := NewRefreshStore(store.NewMemory(), time.Hour)
+
+	first, _ := refreshTokens.Issue(ctx, Subject{UserID: "123"})
+	second, _, err := refreshTokens.Rotate(ctx, first)
+	assert.NoError(t, err)
+
+	// Un attaquant rejoue l'ancien token
+	_, _, err = refreshTokens.Rotate(ctx, first)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	// Le token légitime de la même famille est révoqué lui aussi
+	_, _, err = refreshTokens.Rotate(ctx, second)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshUnknownToken(t *testing.T) {
+	refreshTokens := NewRefreshStore(store.NewMemory(), time.Hour)
+
+	_, _, err := refreshTokens.Rotate(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshRevokeUser(t *testing.T) {
+	ctx := context.Background()
+	refreshTokens := NewRefreshStore(store.NewMemory(), time.Hour)
+
+	laptop, _ := refreshTokens.Issue(ctx, Subject{UserID: "123"})
+	phone, _ := refreshTokens.Issue(ctx, Subject{UserID: "123"})
+
+	assert.NoError(t, refreshTokens.RevokeUser(ctx, "123"))
+
+	_, _, err := refreshTokens.Rotate(ctx, laptop)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, _, err = refreshTokens.Rotate(ctx, phone)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
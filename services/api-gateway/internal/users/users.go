@@ -0,0 +1,292 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Drivers des bases supportées
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Bases de données supportées
+const (
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+)
+
+var (
+	// ErrNotFound est retourné quand l'utilisateur n'existe pas.
+	ErrNotFound = errors.New("user not found")
+	// ErrUsernameTaken est retourné quand le nom d'utilisateur est déjà pris.
+	ErrUsernameTaken = errors.New("username already taken")
+	// ErrInvalidCredentials est retourné pour un utilisateur inconnu ou un mauvais mot de passe.
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// User est un compte du fournisseur d'authentification embarqué.
+type User struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	Email        string    `json:"email"`
+	Role         string    `json:"role"`
+	TenantID     string    `json:"tenant_id"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Repository stocke les utilisateurs en SQLite (une instance) ou PostgreSQL.
+type Repository struct {
+	db     *sql.DB
+	driver string
+	hasher *Hasher
+	// Hash calculé au démarrage, vérifié pour un utilisateur inconnu afin que la
+	// durée de réponse ne révèle pas si le compte existe
+	dummyHash string
+}
+
+// Open ouvre la base ("sqlite" ou "postgres") et crée la table des utilisateurs.
+func Open(ctx context.Context, driver, dsn string, hasher *Hasher) (*Repository, error) {
+	var sqlDriver string
+	switch driver {
+	case SQLite:
+		sqlDriver = "sqlite3"
+	case Postgres:
+		sqlDriver = "postgres"
+	default:
+		return nil, fmt.Errorf("unknown user database driver %q", driver)
+	}
+	db, err := sql.Open(sqlDriver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == SQLite {
+		// SQLite n'accepte qu'un écrivain à la fois
+		db.SetMaxOpenConns(1)
+	}
+
+	repo, err := NewRepository(ctx, db, driver, hasher)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+// NewRepository crée le repository sur une connexion existante et migre le schéma.
+func NewRepository(ctx context.Context, db *sql.DB, driver string, hasher *Hasher) (*Repository, error) {
+	dummyHash, err := hasher.Hash("dummy-password")
+	if err != nil {
+		return nil, err
+	}
+	repo := &Repository{db: db, driver: driver, hasher: hasher, dummyHash: dummyHash}
+	if err := repo.migrate(ctx); err != nil {
+		return nil, fmt.Errorf("failed to migrate users table: %w", err)
+	}
+	return repo, nil
+}
+
+// Close ferme la connexion à la base.
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+func (r *Repository) migrate(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS users (
+		id            TEXT PRIMARY KEY,
+		username      TEXT NOT NULL,
+		username_key  TEXT NOT NULL UNIQUE,
+		email         TEXT NOT NULL,
+		role          TEXT NOT NULL,
+		tenant_id     TEXT NOT NULL,
+		password_hash TEXT NOT NULL,
+		created_at    TIMESTAMP NOT NULL,
+		updated_at    TIMESTAMP NOT NULL
+	)`)
+	return err
+}
+
+// Create enregistre un utilisateur avec le hash de son mot de passe.
+func (r *Repository) Create(ctx context.Context, user *User, password string) error {
+	hash, err := r.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	user.ID = id
+	user.PasswordHash = hash
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	_, err = r.exec(ctx, `INSERT INTO users
+		(id, username, username_key, email, role, tenant_id, password_hash, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ID, user.Username, usernameKey(user.Username), user.Email, user.Role, user.TenantID,
+		user.PasswordHash, user.CreatedAt, user.UpdatedAt)
+	if err != nil && isUniqueViolation(err) {
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+// Authenticate vérifie le mot de passe. Un hash produit avec d'anciens paramètres
+// est remplacé par un hash à jour.
+func (r *Repository) Authenticate(ctx context.Context, username, password string) (*User, error) {
+	user, err := r.FindByUsername(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		r.hasher.Verify(password, r.dummyHash)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := r.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if r.hasher.NeedsRehash(user.PasswordHash) {
+		// Échec non bloquant : le hash sera migré à la prochaine connexion
+		r.SetPassword(ctx, user.ID, password)
+	}
+	return user, nil
+}
+
+// Get retourne un utilisateur par identifiant.
+func (r *Repository) Get(ctx context.Context, id string) (*User, error) {
+	return r.scanOne(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+}
+
+// FindByUsername retourne un utilisateur par nom, sans tenir compte de la casse.
+func (r *Repository) FindByUsername(ctx context.Context, username string) (*User, error) {
+	return r.scanOne(ctx, `SELECT `+userColumns+` FROM users WHERE username_key = ?`, usernameKey(username))
+}
+
+// List retourne les utilisateurs par ordre de création.
+func (r *Repository) List(ctx context.Context) ([]User, error) {
+	rows, err := r.query(ctx, `SELECT `+userColumns+` FROM users ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *user)
+	}
+	return list, rows.Err()
+}
+
+// SetPassword remplace le mot de passe de l'utilisateur.
+func (r *Repository) SetPassword(ctx context.Context, id, password string) error {
+	hash, err := r.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	return r.update(ctx, `UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`, hash, time.Now().UTC(), id)
+}
+
+// SetRole attribue un rôle à l'utilisateur.
+func (r *Repository) SetRole(ctx context.Context, id, role string) (*User, error) {
+	if err := r.update(ctx, `UPDATE users SET role = ?, updated_at = ? WHERE id = ?`, role, time.Now().UTC(), id); err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+const userColumns = `id, username, email, role, tenant_id, password_hash, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	var user User
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.TenantID,
+		&user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *Repository) scanOne(ctx context.Context, query string, args ...interface{}) (*User, error) {
+	return scanUser(r.db.QueryRowContext(ctx, r.rebind(query), args...))
+}
+
+func (r *Repository) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.db.QueryContext(ctx, r.rebind(query), args...)
+}
+
+func (r *Repository) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.db.ExecContext(ctx, r.rebind(query), args...)
+}
+
+func (r *Repository) update(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// rebind remplace les "?" par "$1", "$2"... pour PostgreSQL.
+func (r *Repository) rebind(query string) string {
+	if r.driver != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+func isUniqueViolation(err error) bool {
+	// SQLite : "UNIQUE constraint failed" ; PostgreSQL : code 23505 "duplicate key value"
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint") || strings.Contains(msg, "duplicate key")
+}
+
+func usernameKey(username string) string {
+	return strings.ToLower(username)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
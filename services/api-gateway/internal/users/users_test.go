@@ -0,0 +1,120 @@
+package users
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T, algorithm string) *Repository {
+	hasher, err := NewHasher(algorithm)
+	require.NoError(t, err)
+	repo, err := Open(context.Background(), SQLite, ":memory:", hasher)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestHasher(t *testing.T) {
+	for _, algorithm := range []string{Argon2id, Bcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			hasher, err := NewHasher(algorithm)
+			require.NoError(t, err)
+
+			hash, err := hasher.Hash("correct horse battery staple")
+			require.NoError(t, err)
+			assert.NotContains(t, hash, "correct horse")
+			assert.False(t, hasher.NeedsRehash(hash))
+
+			ok, err := hasher.Verify("correct horse battery staple", hash)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			ok, err = hasher.Verify("wrong password", hash)
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+
+	argon, _ := NewHasher(Argon2id)
+	hash, _ := argon.Hash("secret")
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$"))
+
+	// Un hash bcrypt reste vérifiable après passage à argon2id, mais doit être migré
+	bcryptHasher, _ := NewHasher(Bcrypt)
+	old, _ := bcryptHasher.Hash("secret")
+	ok, err := argon.Verify("secret", old)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, argon.NeedsRehash(old))
+
+	_, err = argon.Verify("secret", "plaintext")
+	assert.ErrorIs(t, err, ErrUnknownHash)
+	_, err = NewHasher("md5")
+	assert.Error(t, err)
+}
+
+func TestRepositoryCreateAndAuthenticate(t *testing.T) {
+	repo := newTestRepository(t, Argon2id)
+	ctx := context.Background()
+
+	user := &User{Username: "Alice", Email: "alice@example.com", Role: "user", TenantID: "acme"}
+	require.NoError(t, repo.Create(ctx, user, "s3cret-Password"))
+	assert.NotEmpty(t, user.ID)
+
+	// Le nom d'utilisateur est unique sans tenir compte de la casse
+	err := repo.Create(ctx, &User{Username: "alice", Email: "other@example.com", Role: "user"}, "another-Password1")
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+
+	authenticated, err := repo.Authenticate(ctx, "ALICE", "s3cret-Password")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, authenticated.ID)
+	assert.Equal(t, "Alice", authenticated.Username)
+	assert.Equal(t, "acme", authenticated.TenantID)
+
+	_, err = repo.Authenticate(ctx, "alice", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = repo.Authenticate(ctx, "bob", "s3cret-Password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// Changement de mot de passe et de rôle
+	require.NoError(t, repo.SetPassword(ctx, user.ID, "n3w-Password"))
+	_, err = repo.Authenticate(ctx, "alice", "s3cret-Password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = repo.Authenticate(ctx, "alice", "n3w-Password")
+	assert.NoError(t, err)
+
+	updated, err := repo.SetRole(ctx, user.ID, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", updated.Role)
+	_, err = repo.SetRole(ctx, "unknown", "admin")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	list, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestAuthenticateMigratesHash(t *testing.T) {
+	repo := newTestRepository(t, Bcrypt)
+	ctx := context.Background()
+	user := &User{Username: "alice", Role: "user"}
+	require.NoError(t, repo.Create(ctx, user, "s3cret-Password"))
+
+	// Passage à argon2id : le hash bcrypt est remplacé à la connexion
+	repo.hasher, _ = NewHasher(Argon2id)
+	_, err := repo.Authenticate(ctx, "alice", "s3cret-Password")
+	require.NoError(t, err)
+	stored, err := repo.Get(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.PasswordHash, "$argon2id$"))
+}
+
+func TestRebindForPostgres(t *testing.T) {
+	repo := &Repository{driver: Postgres}
+	assert.Equal(t, "UPDATE users SET role = $1 WHERE id = $2", repo.rebind("UPDATE users SET role = ? WHERE id = ?"))
+	repo.driver = SQLite
+	assert.Equal(t, "SELECT ?", repo.rebind("SELECT ?"))
+}
@@ -0,0 +1,241 @@
+package validation
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// FieldError est une erreur de validation rattachée à un champ du formulaire.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors regroupe les erreurs de tous les champs.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Field + ": " + err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *Errors) add(field, code, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Rules décrit les règles d'inscription.
+type Rules struct {
+	UsernameMinLength  int
+	UsernameMaxLength  int
+	UsernamePattern    *regexp.Regexp
+	PasswordMinLength  int
+	PasswordMaxLength  int
+	PasswordMinClasses int // parmi minuscules, majuscules, chiffres et symboles
+	// Empreintes SHA-1 (hexadécimal majuscule) des mots de passe compromis
+	Breached map[string]struct{}
+}
+
+// Registration valide les champs d'une inscription et retourne toutes les erreurs.
+func (r *Rules) Registration(username, email, password string) Errors {
+	var errs Errors
+	r.checkUsername(&errs, username)
+	checkEmail(&errs, email)
+	r.checkPassword(&errs, username, password)
+	return errs
+}
+
+// Password valide un nouveau mot de passe (réinitialisation, changement).
+func (r *Rules) Password(username, password string) Errors {
+	var errs Errors
+	r.checkPassword(&errs, username, password)
+	return errs
+}
+
+func (r *Rules) checkUsername(errs *Errors, username string) {
+	length := len([]rune(username))
+	switch {
+	case username == "":
+		errs.add("username", "required", "Username is required")
+	case r.UsernameMinLength > 0 && length < r.UsernameMinLength:
+		errs.add("username", "too_short", "Username must be at least %d characters", r.UsernameMinLength)
+	case r.UsernameMaxLength > 0 && length > r.UsernameMaxLength:
+		errs.add("username", "too_long", "Username must be at most %d characters", r.UsernameMaxLength)
+	case r.UsernamePattern != nil && !r.UsernamePattern.MatchString(username):
+		errs.add("username", "invalid_characters", "Username contains invalid characters")
+	}
+}
+
+func checkEmail(errs *Errors, email string) {
+	if email == "" {
+		errs.add("email", "required", "Email is required")
+		return
+	}
+	// Refuser les formes "Nom <adresse>" acceptées par net/mail
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		errs.add("email", "invalid", "Email address is invalid")
+	}
+}
+
+func (r *Rules) checkPassword(errs *Errors, username, password string) {
+	length := len([]rune(password))
+	if password == "" {
+		errs.add("password", "required", "Password is required")
+		return
+	}
+	if r.PasswordMinLength > 0 && length < r.PasswordMinLength {
+		errs.add("password", "too_short", "Password must be at least %d characters", r.PasswordMinLength)
+	}
+	if r.PasswordMaxLength > 0 && length > r.PasswordMaxLength {
+		// Inutile (et coûteux) de comparer ou de hacher un mot de passe déjà refusé
+		errs.add("password", "too_long", "Password must be at most %d characters", r.PasswordMaxLength)
+		return
+	}
+	if r.PasswordMinClasses > 0 && characterClasses(password) < r.PasswordMinClasses {
+		errs.add("password", "too_simple", "Password must contain at least %d of: lowercase letters, uppercase letters, digits, symbols", r.PasswordMinClasses)
+	}
+	if username != "" && similar(username, password) {
+		errs.add("password", "similar_to_username", "Password is too similar to the username")
+	}
+	if r.IsBreached(password) {
+		errs.add("password", "breached", "Password appears in a list of breached passwords")
+	}
+}
+
+// IsBreached indique si le mot de passe figure dans la liste des mots de passe compromis.
+func (r *Rules) IsBreached(password string) bool {
+	if len(r.Breached) == 0 {
+		return false
+	}
+	_, ok := r.Breached[sha1Hex(password)]
+	return ok
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// maxSimilarityLength borne la distance d'édition, quadratique, calculée sur des
+// champs envoyés par un client non authentifié.
+const maxSimilarityLength = 256
+
+// similar détecte un mot de passe qui contient le nom d'utilisateur (éventuellement
+// inversé), qui y est contenu, ou qui n'en diffère que de quelques caractères.
+func similar(username, password string) bool {
+	u := strings.ToLower(username)
+	p := strings.ToLower(password)
+	if len(u) >= 3 && (strings.Contains(p, u) || strings.Contains(p, reverse(u))) {
+		return true
+	}
+	if strings.Contains(u, p) {
+		return true
+	}
+	if len(u) > maxSimilarityLength || len(p) > maxSimilarityLength {
+		return false
+	}
+	return levenshtein(u, p) <= len([]rune(p))/3
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(prev[j]+1, current[j-1]+1, prev[j-1]+cost)
+		}
+		prev = current
+	}
+	return prev[len(rb)]
+}
+
+// LoadBreached lit une liste de mots de passe compromis, un par ligne. Les lignes
+// peuvent être des mots de passe en clair ou des empreintes SHA-1 au format
+// "HASH" ou "HASH:occurrences" (format Have I Been Pwned).
+func LoadBreached(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if hash, ok := sha1Line(line); ok {
+			breached[hash] = struct{}{}
+			continue
+		}
+		breached[sha1Hex(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return breached, nil
+}
+
+// sha1Line reconnaît une ligne "HASH" ou "HASH:occurrences".
+func sha1Line(line string) (string, bool) {
+	hash, _, _ := strings.Cut(line, ":")
+	if len(hash) != sha1.Size*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", false
+	}
+	return strings.ToUpper(hash), true
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,93 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRules() *Rules {
+	return &Rules{
+		UsernameMinLength:  3,
+		UsernameMaxLength:  16,
+		UsernamePattern:    regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`),
+		PasswordMinLength:  10,
+		PasswordMaxLength:  64,
+		PasswordMinClasses: 3,
+	}
+}
+
+func codes(errs Errors) map[string]string {
+	result := make(map[string]string)
+	for _, err := range errs {
+		result[err.Field] = err.Code
+	}
+	return result
+}
+
+func TestRegistrationValid(t *testing.T) {
+	errs := testRules().Registration("alice", "alice@example.com", "Correct-Horse-42")
+	assert.Empty(t, errs)
+}
+
+func TestRegistrationFieldErrors(t *testing.T) {
+	errs := testRules().Registration("a", "", "a")
+	assert.Equal(t, map[string]string{
+		"username": "too_short",
+		"email":    "required",
+		"password": "similar_to_username",
+	}, codes(errs))
+	// Toutes les erreurs du mot de passe sont retournées
+	assert.GreaterOrEqual(t, len(errs), 4)
+
+	errs = testRules().Registration("-bob", "Bob <bob@example.com>", "Correct-Horse-42")
+	assert.Equal(t, map[string]string{"username": "invalid_characters", "email": "invalid"}, codes(errs))
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	rules := testRules()
+	tests := map[string]string{
+		"short1A!":            "too_short",
+		"alllowercaseletters": "too_simple",
+		"xAlice2024!":         "similar_to_username",
+		"ecila-Strong-99":     "similar_to_username",
+	}
+	for password, code := range tests {
+		errs := rules.Registration("alice", "alice@example.com", password)
+		assert.Equal(t, code, codes(errs)["password"], password)
+	}
+}
+
+func TestLongInputsSkipSimilarity(t *testing.T) {
+	rules := testRules()
+	// Un mot de passe trop long n'est pas comparé au nom d'utilisateur
+	errs := rules.Registration("alice", "alice@example.com", "alice"+strings.Repeat("x", 100))
+	assert.Equal(t, Errors{{Field: "password", Code: "too_long", Message: "Password must be at most 64 characters"}}, errs)
+
+	// Un nom d'utilisateur démesuré ne déclenche pas de distance d'édition
+	assert.False(t, similar(strings.Repeat("a", 1<<20), "Correct-Horse-42"))
+}
+
+func TestBreachedPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	// Un mot de passe en clair et une empreinte SHA-1 au format HIBP ("Password123!")
+	content := "Summer-2024!\n" + sha1Hex("Password123!") + ":3861493\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	breached, err := LoadBreached(path)
+	require.NoError(t, err)
+	rules := testRules()
+	rules.Breached = breached
+
+	assert.True(t, rules.IsBreached("Summer-2024!"))
+	assert.True(t, rules.IsBreached("Password123!"))
+	assert.False(t, rules.IsBreached("Correct-Horse-42"))
+
+	errs := rules.Registration("bob", "bob@example.com", "Password123!")
+	assert.Equal(t, "breached", codes(errs)["password"])
+}
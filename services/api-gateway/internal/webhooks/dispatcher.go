@@ -0,0 +1,286 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/netguard"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// En-têtes envoyés avec chaque livraison
+const (
+	HeaderEvent     = "X-MiniCloud-Event"
+	HeaderDelivery  = "X-MiniCloud-Delivery"
+	HeaderTimestamp = "X-MiniCloud-Timestamp"
+	HeaderSignature = "X-MiniCloud-Signature"
+)
+
+const (
+	// Nombre de tentatives conservées dans le journal de chaque endpoint
+	deliveryLogSize = 100
+	// Nombre de livraisons conservées dans la file des échecs de chaque utilisateur
+	deadLetterSize = 500
+	// Délai maximal entre deux tentatives
+	maxRetryDelay = 10 * time.Minute
+)
+
+// Payload est le corps JSON envoyé aux endpoints.
+type Payload struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	UserID    string                 `json:"user_id"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Attempt est une tentative de livraison, journalisée par endpoint.
+type Attempt struct {
+	DeliveryID string    `json:"delivery_id"`
+	EndpointID string    `json:"endpoint_id"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Duration   string    `json:"duration"`
+	At         time.Time `json:"at"`
+}
+
+// DeadLetter est une livraison abandonnée après toutes les tentatives.
+type DeadLetter struct {
+	EndpointID string    `json:"endpoint_id"`
+	URL        string    `json:"url"`
+	Payload    Payload   `json:"payload"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// Options configure le dispatcher.
+type Options struct {
+	MaxAttempts int
+	RetryDelay  time.Duration
+	Timeout     time.Duration
+	// Guard filtre les adresses contactées ; sans Guard, les réseaux internes sont refusés
+	Guard  *netguard.Guard
+	Client *http.Client
+}
+
+// Dispatcher livre les évènements aux endpoints abonnés. Il implémente notify.Notifier.
+type Dispatcher struct {
+	repo        *Repository
+	store       store.Store
+	client      *http.Client
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+// NewDispatcher crée un dispatcher. Les livraisons sont asynchrones.
+func NewDispatcher(repo *Repository, st store.Store, opts Options) *Dispatcher {
+	client := opts.Client
+	if client == nil {
+		guard := opts.Guard
+		if guard == nil {
+			guard, _ = netguard.New(nil)
+		}
+		client = &http.Client{Timeout: opts.Timeout, Transport: guard.Transport()}
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	return &Dispatcher{
+		repo:        repo,
+		store:       st,
+		client:      client,
+		maxAttempts: opts.MaxAttempts,
+		retryDelay:  opts.RetryDelay,
+	}
+}
+
+func deliveryLogKey(endpointID string) string {
+	return fmt.Sprintf("webhook:deliveries:%s", endpointID)
+}
+
+func deadLetterKey(userID string) string {
+	return fmt.Sprintf("webhooks:dead_letter:%s", userID)
+}
+
+// Notify envoie l'évènement à tous les endpoints abonnés.
+func (d *Dispatcher) Notify(ctx context.Context, event notify.Event) error {
+	endpoints, err := d.repo.Subscribers(ctx, event.TenantID, event.UserID, event.Type)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	createdAt := event.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+	payload := Payload{
+		ID:        id,
+		Type:      event.Type,
+		UserID:    event.UserID,
+		Data:      event.Data,
+		CreatedAt: createdAt,
+	}
+
+	for _, endpoint := range endpoints {
+		go d.deliver(endpoint, payload)
+	}
+	return nil
+}
+
+// deliver tente la livraison avec un backoff exponentiel, puis passe en dead letter.
+func (d *Dispatcher) deliver(endpoint Endpoint, payload Payload) {
+	ctx := context.Background()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("webhooks: failed to marshal payload %s: %v", payload.ID, err)
+		return
+	}
+
+	delay := d.retryDelay
+	var lastErr string
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(delay)
+			delay *= 2
+			if delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+		}
+
+		start := time.Now()
+		status, err := d.send(ctx, endpoint, payload, body)
+		entry := Attempt{
+			DeliveryID: payload.ID,
+			EndpointID: endpoint.ID,
+			Event:      payload.Type,
+			Attempt:    attempt,
+			StatusCode: status,
+			Duration:   time.Since(start).String(),
+			At:         start.UTC(),
+		}
+		if err != nil {
+			entry.Error = err.Error()
+			lastErr = err.Error()
+		}
+		d.record(ctx, deliveryLogKey(endpoint.ID), entry, deliveryLogSize)
+
+		if err == nil {
+			return
+		}
+	}
+
+	d.record(ctx, deadLetterKey(endpoint.UserID), DeadLetter{
+		EndpointID: endpoint.ID,
+		URL:        endpoint.URL,
+		Payload:    payload,
+		Attempts:   d.maxAttempts,
+		LastError:  lastErr,
+		FailedAt:   time.Now().UTC(),
+	}, deadLetterSize)
+}
+
+// send effectue une tentative. Toute réponse hors 2xx est un échec.
+func (d *Dispatcher) send(ctx context.Context, endpoint Endpoint, payload Payload, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mini-cloud-webhooks/1.0")
+	req.Header.Set(HeaderEvent, payload.Type)
+	req.Header.Set(HeaderDelivery, payload.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) record(ctx context.Context, key string, v interface{}, max int) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := d.store.LPush(ctx, key, string(data), max); err != nil {
+		log.Printf("webhooks: failed to record %s: %v", key, err)
+	}
+}
+
+// Deliveries retourne le journal des tentatives d'un endpoint, les plus récentes d'abord.
+func (d *Dispatcher) Deliveries(ctx context.Context, endpointID string) ([]Attempt, error) {
+	items, err := d.store.LRange(ctx, deliveryLogKey(endpointID), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	attempts := make([]Attempt, 0, len(items))
+	for _, item := range items {
+		var a Attempt
+		if err := json.Unmarshal([]byte(item), &a); err == nil {
+			attempts = append(attempts, a)
+		}
+	}
+	return attempts, nil
+}
+
+// DeadLetters retourne les livraisons abandonnées de l'utilisateur.
+func (d *Dispatcher) DeadLetters(ctx context.Context, userID string) ([]DeadLetter, error) {
+	items, err := d.store.LRange(ctx, deadLetterKey(userID), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	letters := make([]DeadLetter, 0, len(items))
+	for _, item := range items {
+		var l DeadLetter
+		if err := json.Unmarshal([]byte(item), &l); err == nil {
+			letters = append(letters, l)
+		}
+	}
+	return letters, nil
+}
+
+// Sign calcule la signature "sha256=<hex>" de HMAC(secret, timestamp + "." + body).
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify vérifie la signature d'une livraison reçue. Elle est destinée aux
+// récepteurs (et aux tests avec httptest).
+func Verify(secret string, r *http.Request, body []byte) bool {
+	expected := Sign(secret, r.Header.Get(HeaderTimestamp), body)
+	return hmac.Equal([]byte(expected), []byte(r.Header.Get(HeaderSignature)))
+}
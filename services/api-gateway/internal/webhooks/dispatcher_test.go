@@ -0,0 +1,131 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/netguard"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/notify"
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDispatcher(t *testing.T, url string, events []string) (*Dispatcher, *Endpoint) {
+	st := store.NewMemory()
+	repo := NewRepository(st)
+	endpoint := &Endpoint{UserID: "user-1", URL: url, Events: events}
+	assert.NoError(t, repo.Create(context.Background(), endpoint))
+
+	// Les récepteurs de test écoutent sur la boucle locale
+	guard, err := netguard.New([]string{"127.0.0.1"})
+	assert.NoError(t, err)
+	dispatcher := NewDispatcher(repo, st, Options{
+		MaxAttempts: 3,
+		RetryDelay:  time.Millisecond,
+		Timeout:     time.Second,
+		Guard:       guard,
+	})
+	return dispatcher, endpoint
+}
+
+func TestDispatcherSignedDelivery(t *testing.T) {
+	received := make(chan bool, 1)
+	var secret string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "file.uploaded", r.Header.Get(HeaderEvent))
+		received <- Verify(secret, r, body)
+	}))
+	defer receiver.Close()
+
+	dispatcher, endpoint := setupDispatcher(t, receiver.URL, []string{"file.uploaded"})
+	secret = endpoint.Secret
+
+	err := dispatcher.Notify(context.Background(), notify.Event{Type: "file.uploaded", UserID: "user-1"})
+	assert.NoError(t, err)
+
+	select {
+	case valid := <-received:
+		assert.True(t, valid)
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook not delivered")
+	}
+
+	assert.Eventually(t, func() bool {
+		attempts, _ := dispatcher.Deliveries(context.Background(), endpoint.ID)
+		return len(attempts) == 1 && attempts[0].StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDispatcherSkipsUnsubscribed(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer receiver.Close()
+
+	dispatcher, _ := setupDispatcher(t, receiver.URL, []string{"file.deleted"})
+
+	dispatcher.Notify(context.Background(), notify.Event{Type: "file.uploaded", UserID: "user-1"})
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestDispatcherRetriesThenDeadLetter(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	dispatcher, endpoint := setupDispatcher(t, receiver.URL, []string{"*"})
+
+	dispatcher.Notify(context.Background(), notify.Event{Type: "file.deleted", UserID: "user-1"})
+
+	assert.Eventually(t, func() bool {
+		letters, _ := dispatcher.DeadLetters(context.Background(), "user-1")
+		return len(letters) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	attempts, _ := dispatcher.Deliveries(context.Background(), endpoint.ID)
+	assert.Len(t, attempts, 3)
+}
+
+func TestDispatcherBlocksInternalAddresses(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer receiver.Close()
+
+	// Sans liste blanche, la boucle locale est refusée à la connexion
+	st := store.NewMemory()
+	repo := NewRepository(st)
+	endpoint := &Endpoint{UserID: "user-1", URL: receiver.URL, Events: []string{"*"}}
+	assert.NoError(t, repo.Create(context.Background(), endpoint))
+	dispatcher := NewDispatcher(repo, st, Options{MaxAttempts: 1, Timeout: time.Second})
+
+	dispatcher.Notify(context.Background(), notify.Event{Type: "file.deleted", UserID: "user-1"})
+
+	assert.Eventually(t, func() bool {
+		letters, _ := dispatcher.DeadLetters(context.Background(), "user-1")
+		return len(letters) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	letters, _ := dispatcher.DeadLetters(context.Background(), "user-1")
+	assert.Contains(t, letters[0].LastError, netguard.ErrBlockedAddress.Error())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestRepositoryRejectsUnknownEvent(t *testing.T) {
+	repo := NewRepository(store.NewMemory())
+	err := repo.Create(context.Background(), &Endpoint{UserID: "user-1", URL: "http://example.com", Events: []string{"file.exploded"}})
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+}
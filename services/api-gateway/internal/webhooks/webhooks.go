@@ -0,0 +1,202 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mtk14m/mini-cloud/api-gateway/internal/store"
+)
+
+// Évènements auxquels un endpoint peut s'abonner
+var Events = []string{
+	"file.uploaded",
+	"file.deleted",
+	"file_request.uploaded",
+	"import.completed",
+	"import.failed",
+	"user.registered",
+	"share.accessed",
+}
+
+var (
+	// ErrNotFound est retourné quand l'endpoint n'existe pas ou n'appartient pas à l'utilisateur.
+	ErrNotFound = errors.New("webhook endpoint not found")
+	// ErrUnknownEvent est retourné pour un abonnement à un évènement inconnu.
+	ErrUnknownEvent = errors.New("unknown webhook event")
+)
+
+// Endpoint est une URL enregistrée par un utilisateur pour recevoir des évènements.
+// Un endpoint global reçoit les évènements de tous les utilisateurs de son tenant.
+type Endpoint struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	TenantID  string    `json:"tenant_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Global    bool      `json:"global,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribed indique si l'endpoint doit recevoir ce type d'évènement.
+func (e *Endpoint) Subscribed(eventType string) bool {
+	for _, ev := range e.Events {
+		if ev == "*" || ev == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Repository persiste les endpoints dans le store.
+type Repository struct {
+	store store.Store
+}
+
+// NewRepository crée un repository adossé au store.
+func NewRepository(st store.Store) *Repository {
+	return &Repository{store: st}
+}
+
+func globalKey(tenantID string) string {
+	return fmt.Sprintf("webhooks:global:%s", tenantID)
+}
+
+func endpointKey(id string) string {
+	return fmt.Sprintf("webhook:%s", id)
+}
+
+func userKey(userID string) string {
+	return fmt.Sprintf("webhooks:user:%s", userID)
+}
+
+// Create enregistre un endpoint et génère son secret de signature.
+func (r *Repository) Create(ctx context.Context, endpoint *Endpoint) error {
+	for _, ev := range endpoint.Events {
+		if !validEvent(ev) {
+			return fmt.Errorf("%w: %s", ErrUnknownEvent, ev)
+		}
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return err
+	}
+	endpoint.ID = id
+	endpoint.Secret = secret
+	endpoint.CreatedAt = time.Now().UTC()
+
+	if err := store.SetJSON(ctx, r.store, endpointKey(id), endpoint, 0); err != nil {
+		return err
+	}
+	if endpoint.Global {
+		if err := r.store.SAdd(ctx, globalKey(endpoint.TenantID), id); err != nil {
+			return err
+		}
+	}
+	return r.store.SAdd(ctx, userKey(endpoint.UserID), id)
+}
+
+// Get retourne un endpoint appartenant à userID.
+func (r *Repository) Get(ctx context.Context, userID, id string) (*Endpoint, error) {
+	var endpoint Endpoint
+	if err := store.GetJSON(ctx, r.store, endpointKey(id), &endpoint); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if endpoint.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return &endpoint, nil
+}
+
+// List retourne les endpoints créés par l'utilisateur.
+func (r *Repository) List(ctx context.Context, userID string) ([]Endpoint, error) {
+	return r.load(ctx, userKey(userID))
+}
+
+// Delete supprime un endpoint de l'utilisateur.
+func (r *Repository) Delete(ctx context.Context, userID, id string) error {
+	endpoint, err := r.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := r.store.Delete(ctx, endpointKey(id)); err != nil {
+		return err
+	}
+	r.store.SRem(ctx, globalKey(endpoint.TenantID), id)
+	return r.store.SRem(ctx, userKey(userID), id)
+}
+
+// Subscribers retourne les endpoints à notifier pour un évènement d'un utilisateur :
+// les siens et les endpoints globaux de son tenant.
+func (r *Repository) Subscribers(ctx context.Context, tenantID, userID, eventType string) ([]Endpoint, error) {
+	own, err := r.load(ctx, userKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	global, err := r.load(ctx, globalKey(tenantID))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var subscribers []Endpoint
+	for _, endpoint := range append(own, global...) {
+		if seen[endpoint.ID] || !endpoint.Subscribed(eventType) {
+			continue
+		}
+		seen[endpoint.ID] = true
+		subscribers = append(subscribers, endpoint)
+	}
+	return subscribers, nil
+}
+
+func (r *Repository) load(ctx context.Context, indexKey string) ([]Endpoint, error) {
+	ids, err := r.store.SMembers(ctx, indexKey)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]Endpoint, 0, len(ids))
+	for _, id := range ids {
+		var endpoint Endpoint
+		if err := store.GetJSON(ctx, r.store, endpointKey(id), &endpoint); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+func validEvent(eventType string) bool {
+	if eventType == "*" {
+		return true
+	}
+	for _, ev := range Events {
+		if ev == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,219 @@
+// Package identity transmet l'identité de l'utilisateur de la gateway aux services
+// internes. La gateway retire les headers d'identité fournis par le client, pose
+// X-User-ID, X-User-Role, X-Tenant-ID, X-Request-ID et, pour une impersonation,
+// X-Actor-ID, et les signe (HMAC-SHA256)
+// avec un secret partagé. Les services vérifient la signature avec Verifier au lieu
+// de revérifier le JWT ou de faire confiance aux headers.
+//
+// Le paquet n'utilise que la bibliothèque standard et peut être importé par les services.
+package identity
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers posés par la gateway.
+const (
+	HeaderUserID    = "X-User-ID"
+	HeaderRole      = "X-User-Role"
+	HeaderTenantID  = "X-Tenant-ID"
+	HeaderRequestID = "X-Request-ID"
+	HeaderActorID   = "X-Actor-ID"
+	HeaderTimestamp = "X-Identity-Timestamp"
+	HeaderSignature = "X-Identity-Signature"
+)
+
+// Préfixe de version de la signature, pour pouvoir faire évoluer le format
+const signatureVersion = "v1"
+
+// DefaultMaxSkew est l'âge maximal d'une signature accepté par défaut.
+const DefaultMaxSkew = time.Minute
+
+var (
+	ErrMissingSignature = errors.New("identity: missing signature")
+	ErrInvalidSignature = errors.New("identity: invalid signature")
+	ErrExpiredSignature = errors.New("identity: signature expired")
+)
+
+// Identity est l'identité transmise avec une requête. UserID est vide pour une
+// requête anonyme (connexion, inscription).
+type Identity struct {
+	UserID    string
+	Role      string
+	TenantID  string
+	RequestID string
+	// ActorID est l'admin qui agit au nom de UserID (impersonation) ; vide sinon
+	ActorID string
+}
+
+type contextKey struct{}
+
+// NewContext retourne un contexte portant l'identité.
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext retourne l'identité du contexte.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(Identity)
+	return id, ok
+}
+
+// StripHeaders retire les headers d'identité, y compris ceux ajoutés par un client
+// (X-User-*, X-Identity-*).
+func StripHeaders(h http.Header) {
+	for name := range h {
+		if strings.HasPrefix(name, "X-User-") || strings.HasPrefix(name, "X-Identity-") {
+			delete(h, name)
+		}
+	}
+	h.Del(HeaderTenantID)
+	h.Del(HeaderRequestID)
+	h.Del(HeaderActorID)
+}
+
+// Signer signe l'identité des requêtes sortantes de la gateway.
+type Signer struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewSigner crée un signer avec le secret partagé avec les services. Sans secret,
+// les headers sont posés sans signature (développement local).
+func NewSigner(secret string) *Signer {
+	return &Signer{secret: []byte(secret), now: time.Now}
+}
+
+// Sign remplace les headers d'identité de la requête par ceux de id et les signe.
+// La signature couvre aussi la méthode, le chemin et la query string : elle ne peut
+// pas être rejouée sur une autre route ni avec d'autres paramètres.
+func (s *Signer) Sign(req *http.Request, id Identity) {
+	StripHeaders(req.Header)
+	setHeader(req.Header, HeaderUserID, id.UserID)
+	setHeader(req.Header, HeaderRole, id.Role)
+	setHeader(req.Header, HeaderTenantID, id.TenantID)
+	setHeader(req.Header, HeaderRequestID, id.RequestID)
+	setHeader(req.Header, HeaderActorID, id.ActorID)
+	if len(s.secret) == 0 {
+		return
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, signatureVersion+"="+sign(s.secret, req.Method, req.URL.Path, req.URL.RawQuery, timestamp, id))
+}
+
+func setHeader(h http.Header, name, value string) {
+	if value != "" {
+		h.Set(name, value)
+	}
+}
+
+// Transport signe chaque requête avec l'identité de son contexte (voir NewContext).
+// Il s'utilise comme Transport d'un http.Client ou d'un httputil.ReverseProxy.
+type Transport struct {
+	Signer *Signer
+	// Base est le transport sous-jacent ; http.DefaultTransport s'il est nil
+	Base http.RoundTripper
+}
+
+// RoundTrip implémente http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id, _ := FromContext(req.Context())
+	// Un RoundTripper ne doit pas modifier la requête reçue
+	signed := req.Clone(req.Context())
+	t.Signer.Sign(signed, id)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(signed)
+}
+
+// Verifier vérifie l'identité signée par la gateway, côté service.
+type Verifier struct {
+	secret  []byte
+	maxSkew time.Duration
+	now     func() time.Time
+}
+
+// NewVerifier crée un verifier ; les signatures plus vieilles que maxSkew sont
+// refusées (DefaultMaxSkew si maxSkew vaut 0).
+func NewVerifier(secret string, maxSkew time.Duration) *Verifier {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	return &Verifier{secret: []byte(secret), maxSkew: maxSkew, now: time.Now}
+}
+
+// Verify retourne l'identité de la requête si sa signature est valide et récente.
+func (v *Verifier) Verify(req *http.Request) (Identity, error) {
+	signature, ok := strings.CutPrefix(req.Header.Get(HeaderSignature), signatureVersion+"=")
+	timestamp := req.Header.Get(HeaderTimestamp)
+	if !ok || signature == "" || timestamp == "" {
+		return Identity{}, ErrMissingSignature
+	}
+	// Sans secret, n'importe qui pourrait signer : tout refuser
+	if len(v.secret) == 0 {
+		return Identity{}, ErrInvalidSignature
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return Identity{}, ErrInvalidSignature
+	}
+
+	id := Identity{
+		UserID:    req.Header.Get(HeaderUserID),
+		Role:      req.Header.Get(HeaderRole),
+		TenantID:  req.Header.Get(HeaderTenantID),
+		RequestID: req.Header.Get(HeaderRequestID),
+		ActorID:   req.Header.Get(HeaderActorID),
+	}
+	expected := sign(v.secret, req.Method, req.URL.Path, req.URL.RawQuery, timestamp, id)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return Identity{}, ErrInvalidSignature
+	}
+
+	age := v.now().Sub(time.Unix(seconds, 0))
+	if age > v.maxSkew || age < -v.maxSkew {
+		return Identity{}, ErrExpiredSignature
+	}
+	return id, nil
+}
+
+// Middleware refuse (401) les requêtes sans identité signée valide et ajoute
+// l'identité au contexte de la requête (voir FromContext).
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := v.Verify(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+func sign(secret []byte, method, path, query, timestamp string, id Identity) string {
+	fields := []string{
+		signatureVersion, method, path, query, timestamp,
+		id.UserID, id.Role, id.TenantID, id.RequestID,
+	}
+	// Ajouté seulement s'il est présent : la signature des autres requêtes ne change
+	// pas, et un service qui ne connaît pas X-Actor-ID refuse les impersonations
+	if id.ActorID != "" {
+		fields = append(fields, id.ActorID)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
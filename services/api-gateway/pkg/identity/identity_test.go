@@ -0,0 +1,134 @@
+package identity
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	signer := NewSigner("shared-secret")
+	verifier := NewVerifier("shared-secret", time.Minute)
+	id := Identity{UserID: "123", Role: "admin", TenantID: "acme", RequestID: "req-1"}
+
+	req := httptest.NewRequest("GET", "/files/42", nil)
+	// Headers fournis par le client : ils ne doivent pas survivre à la signature
+	req.Header.Set("X-User-ID", "attacker")
+	req.Header.Set("X-User-Name", "root")
+	req.Header.Set("X-Identity-Signature", "v1=forged")
+	signer.Sign(req, id)
+
+	assert.Empty(t, req.Header.Get("X-User-Name"))
+	got, err := verifier.Verify(req)
+	require.NoError(t, err)
+	assert.Equal(t, id, got)
+
+	// Impersonation : l'admin est signé avec l'identité et X-Actor-ID du client est ignoré
+	impersonated := httptest.NewRequest("GET", "/files/42", nil)
+	impersonated.Header.Set(HeaderActorID, "attacker")
+	signer.Sign(impersonated, Identity{UserID: "123", Role: "user", ActorID: "admin-1"})
+	got, err = verifier.Verify(impersonated)
+	require.NoError(t, err)
+	assert.Equal(t, "admin-1", got.ActorID)
+	impersonated.Header.Del(HeaderActorID)
+	_, err = verifier.Verify(impersonated)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// Anonyme : pas d'utilisateur, mais la requête reste signée
+	anonymous := httptest.NewRequest("POST", "/login", nil)
+	signer.Sign(anonymous, Identity{RequestID: "req-2"})
+	got, err = verifier.Verify(anonymous)
+	require.NoError(t, err)
+	assert.Equal(t, Identity{RequestID: "req-2"}, got)
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	signer := NewSigner("shared-secret")
+	verifier := NewVerifier("shared-secret", time.Minute)
+	id := Identity{UserID: "123", Role: "user", TenantID: "acme"}
+
+	tests := []struct {
+		name   string
+		tamper func(req *http.Request)
+		err    error
+	}{
+		{"unchanged", func(req *http.Request) {}, nil},
+		{"role changed", func(req *http.Request) { req.Header.Set(HeaderRole, "admin") }, ErrInvalidSignature},
+		{"tenant removed", func(req *http.Request) { req.Header.Del(HeaderTenantID) }, ErrInvalidSignature},
+		{"actor added", func(req *http.Request) { req.Header.Set(HeaderActorID, "admin-1") }, ErrInvalidSignature},
+		{"other route", func(req *http.Request) { req.URL.Path = "/admin/users" }, ErrInvalidSignature},
+		{"other method", func(req *http.Request) { req.Method = "DELETE" }, ErrInvalidSignature},
+		{"query changed", func(req *http.Request) { req.URL.RawQuery = "version=2" }, ErrInvalidSignature},
+		{"query removed", func(req *http.Request) { req.URL.RawQuery = "" }, ErrInvalidSignature},
+		{"no signature", func(req *http.Request) { req.Header.Del(HeaderSignature) }, ErrMissingSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/files/42?version=1", nil)
+			signer.Sign(req, id)
+			tt.tamper(req)
+			_, err := verifier.Verify(req)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	// Autre secret
+	req := httptest.NewRequest("GET", "/files/42", nil)
+	signer.Sign(req, id)
+	_, err := NewVerifier("other-secret", time.Minute).Verify(req)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestVerifyRejectsOldSignature(t *testing.T) {
+	signer := NewSigner("shared-secret")
+	signer.now = func() time.Time { return time.Now().Add(-2 * time.Minute) }
+	req := httptest.NewRequest("GET", "/files/42", nil)
+	signer.Sign(req, Identity{UserID: "123"})
+
+	_, err := NewVerifier("shared-secret", time.Minute).Verify(req)
+	assert.ErrorIs(t, err, ErrExpiredSignature)
+}
+
+func TestUnsignedWithoutSecret(t *testing.T) {
+	req := httptest.NewRequest("GET", "/files/42", nil)
+	NewSigner("").Sign(req, Identity{UserID: "123", Role: "user"})
+
+	// Les headers sont posés mais un verifier sans secret ne les accepte jamais
+	assert.Equal(t, "123", req.Header.Get(HeaderUserID))
+	assert.Empty(t, req.Header.Get(HeaderSignature))
+	_, err := NewVerifier("", time.Minute).Verify(req)
+	assert.Error(t, err)
+}
+
+func TestTransportAndMiddleware(t *testing.T) {
+	var received Identity
+	upstream := httptest.NewServer(NewVerifier("shared-secret", 0).Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received, _ = FromContext(r.Context())
+		}),
+	))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: &Transport{Signer: NewSigner("shared-secret")}}
+	id := Identity{UserID: "123", Role: "user", TenantID: "acme", RequestID: "req-1"}
+	req, _ := http.NewRequestWithContext(NewContext(context.Background(), id), "GET", upstream.URL+"/files", nil)
+	req.Header.Set("X-User-Role", "admin")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, id, received)
+	// La requête de l'appelant n'est pas modifiée
+	assert.Equal(t, "admin", req.Header.Get("X-User-Role"))
+
+	// Sans signature, le service refuse la requête
+	resp, err = http.Get(upstream.URL + "/files")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}